@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+)
+
+const (
+	// 매 interval_days일마다 반복할 때 최대 간격
+	maxIntervalDays = 3650
+	// 자동으로 만드는 기념일 범위 (10000일, 50주년까지)
+	maxMilestoneDays = 10000
+	maxMilestoneYears = 50
+	// 반복 일정의 다음 날짜를 찾을 때 확인하는 최대 개월 수
+	maxNextOccurrenceMonths = 12*10 + 1
+	connectionStartDateLayout = "2006/01/02"
+	// end_time 없이 추가한 시간 일정의 길이
+	defaultEventDuration = time.Hour
+	maxEventDays = 366
+	maxEventContentsLength = 255
+	maxEventNotesLength = 5000
+	// 기간으로 조회할 때 최대 일수
+	maxAnniversaryRangeDays = 366
+)
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// 달에 없는 날짜(2월 30일 등)는 그 달의 마지막 날로 맞춤
+func clampedDate(year int, month time.Month, day int) time.Time {
+	if last := daysInMonth(year, month); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// 저장된 일정의 양력 날짜, 음력이면 양력으로 변환
+func anniversaryStartDate(anniversary model.AnniversaryData) (time.Time, bool) {
+	if anniversary.Is_lunar {
+		return lunarToSolar(anniversary.Year, anniversary.Month, anniversary.Date, anniversary.Is_leap_month)
+	}
+	t := time.Date(anniversary.Year, time.Month(anniversary.Month), anniversary.Date, 0, 0, 0, 0, time.UTC)
+	if t.Year() != anniversary.Year || int(t.Month()) != anniversary.Month || t.Day() != anniversary.Date {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// 일정 추가 요청 확인, 잘못된 값이면 에러 메시지 리턴
+func validateAnniversary(anniversary model.AnniversaryData) string {
+	if anniversary.Contents == "" {
+		return "EMPTY_CONTENTS"
+	}
+	switch anniversary.Recurrence {
+	case "", "yearly":
+	case "monthly":
+		if anniversary.Is_lunar {
+			return "LUNAR_NOT_SUPPORTED"
+		}
+	case "days":
+		if anniversary.Is_lunar {
+			return "LUNAR_NOT_SUPPORTED"
+		}
+		if anniversary.Interval_days < 1 || anniversary.Interval_days > maxIntervalDays {
+			return "INVALID_INTERVAL"
+		}
+	default:
+		return "INVALID_RECURRENCE"
+	}
+	if anniversary.Is_leap_month && !anniversary.Is_lunar {
+		return "INVALID_DATE"
+	}
+	if _, ok := anniversaryStartDate(anniversary); !ok {
+		return "INVALID_DATE"
+	}
+	return ""
+}
+
+// 일정이 year년 month월에 있는 양력 날짜들
+// yearly는 매년 같은 날(음력이면 매년 음력 같은 날), monthly는 매달 같은 날, days는 저장된 날부터 interval_days일마다
+func occurrencesInMonth(anniversary model.AnniversaryData, year int, month time.Month) []time.Time {
+	start, ok := anniversaryStartDate(anniversary)
+	if !ok {
+		return nil
+	}
+	monthStart := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	inMonth := func(t time.Time) bool {
+		return !t.Before(monthStart) && t.Before(monthEnd) && !t.Before(start)
+	}
+
+	occurrences := []time.Time{}
+	switch anniversary.Recurrence {
+	case "":
+		if inMonth(start) {
+			occurrences = append(occurrences, start)
+		}
+	case "yearly":
+		if anniversary.Is_lunar {
+			// 음력 12월은 다음 해 양력 1, 2월이 될 수 있어서 전년도 음력도 확인
+			for lunarYear := year - 1; lunarYear <= year; lunarYear++ {
+				if t, ok := lunarToSolar(lunarYear, anniversary.Month, anniversary.Date, anniversary.Is_leap_month); ok && inMonth(t) {
+					occurrences = append(occurrences, t)
+				}
+			}
+		} else if int(month) == anniversary.Month {
+			if t := clampedDate(year, month, anniversary.Date); inMonth(t) {
+				occurrences = append(occurrences, t)
+			}
+		}
+	case "monthly":
+		if t := clampedDate(year, month, anniversary.Date); inMonth(t) {
+			occurrences = append(occurrences, t)
+		}
+	case "days":
+		interval := anniversary.Interval_days
+		t := start
+		if start.Before(monthStart) {
+			days := int(monthStart.Sub(start).Hours() / 24)
+			t = start.AddDate(0, 0, (days+interval-1)/interval*interval)
+		}
+		for ; t.Before(monthEnd); t = t.AddDate(0, 0, interval) {
+			occurrences = append(occurrences, t)
+		}
+	}
+	return occurrences
+}
+
+// 사귄 날(start_date)부터 계산한 100일 단위, 1년 단위 기념일 중 [from, to)에 있는 것, loc 기준 하루 종일 일정
+// 사귄 날을 1일로 세서 100일은 사귄 날부터 99일 뒤
+func milestonesInRange(conn_id int, start_date string, from, to time.Time, loc *time.Location) []model.AnniversaryData {
+	start, err := time.Parse(connectionStartDateLayout, start_date)
+	if err != nil {
+		return nil
+	}
+
+	milestones := []model.AnniversaryData{}
+	add := func(t time.Time, contents string) {
+		dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if !dayStart.Before(to) || !dayStart.AddDate(0, 0, 1).After(from) {
+			return
+		}
+		milestone := model.AnniversaryData{
+			Connection_id: conn_id,
+			Contents: contents,
+			Is_milestone: true,
+			Origin_date: start.Format("2006-01-02"),
+		}
+		formatEventTimes(&milestone, dayStart, dayStart.AddDate(0, 0, 1), true, loc)
+		milestones = append(milestones, milestone)
+	}
+	for days := 100; days <= maxMilestoneDays; days += 100 {
+		add(start.AddDate(0, 0, days-1), fmt.Sprintf("%d일", days))
+	}
+	for years := 1; years <= maxMilestoneYears; years++ {
+		add(clampedDate(start.Year()+years, start.Month(), start.Day()), fmt.Sprintf("%d주년", years))
+	}
+	return milestones
+}
+
+// 일정 시간대, 잘못 저장된 값이면 서버 시간대
+func eventLocation(anniversary model.AnniversaryData) *time.Location {
+	loc, err := time.LoadLocation(anniversary.Timezone)
+	if err != nil {
+		return getTimeNow().Location()
+	}
+	return loc
+}
+
+// DB에 저장된 일정의 시작/끝 시간
+func eventTimes(anniversary model.AnniversaryData) (time.Time, time.Time, bool) {
+	storageLoc := getTimeNow().Location()
+	start, err1 := time.ParseInLocation(chatTimeLayout, anniversary.Start_time, storageLoc)
+	end, err2 := time.ParseInLocation(chatTimeLayout, anniversary.End_time, storageLoc)
+	return start, end, err1 == nil && err2 == nil
+}
+
+// 응답용 시간 형식으로 바꾸고 year, month, date도 loc 기준 시작 날짜로 맞춤
+// 하루 종일 일정은 날짜만, end_time은 끝나는 날(포함)로 보냄
+func formatEventTimes(anniversary *model.AnniversaryData, start, end time.Time, isAllDay bool, loc *time.Location) {
+	start, end = start.In(loc), end.In(loc)
+	anniversary.Is_all_day = isAllDay
+	anniversary.Timezone = loc.String()
+	anniversary.Year, anniversary.Month, anniversary.Date = start.Year(), int(start.Month()), start.Day()
+	if isAllDay {
+		anniversary.Start_time = start.Format("2006-01-02")
+		anniversary.End_time = end.AddDate(0, 0, -1).Format("2006-01-02")
+		return
+	}
+	anniversary.Start_time = start.Format(time.RFC3339)
+	anniversary.End_time = end.Format(time.RFC3339)
+}
+
+// 일정 추가 요청의 시간을 확인하고 DB에 저장할 형식으로 바꿈, 잘못된 값이면 에러 메시지 리턴
+// start_time이 없으면 예전처럼 year, month, date로 하루 종일 일정을 만들고, 음력 일정은 항상 하루 종일 일정
+// end_time이 없으면 하루 종일 일정은 그 날 하루, 시간 일정은 1시간
+func normalizeEvent(anniversary *model.AnniversaryData) string {
+	if anniversary.Timezone == "" {
+		anniversary.Timezone = getTimeNow().Location().String()
+	}
+	loc, err := time.LoadLocation(anniversary.Timezone)
+	if err != nil {
+		return "INVALID_TIMEZONE"
+	}
+	if utf8.RuneCountInString(anniversary.Contents) > maxEventContentsLength || utf8.RuneCountInString(anniversary.Location) > maxEventContentsLength {
+		return "TOO_LONG_CONTENTS"
+	}
+	if utf8.RuneCountInString(anniversary.Notes) > maxEventNotesLength {
+		return "TOO_LONG_NOTES"
+	}
+
+	var start time.Time
+	if anniversary.Is_lunar || anniversary.Start_time == "" {
+		date, ok := anniversaryStartDate(*anniversary)
+		if !ok {
+			return "INVALID_DATE"
+		}
+		start = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		anniversary.Is_all_day = true
+	} else {
+		t, isDate, ok := parseRangeTime(anniversary.Start_time, loc)
+		if !ok {
+			return "INVALID_START_TIME"
+		}
+		start = t
+		if isDate {
+			anniversary.Is_all_day = true
+		} else if anniversary.Is_all_day {
+			return "INVALID_START_TIME"
+		}
+	}
+
+	var end time.Time
+	switch {
+	case anniversary.End_time == "" && anniversary.Is_all_day:
+		end = start.AddDate(0, 0, 1)
+	case anniversary.End_time == "":
+		end = start.Add(defaultEventDuration)
+	default:
+		t, isDate, ok := parseRangeTime(anniversary.End_time, loc)
+		if !ok || isDate != anniversary.Is_all_day {
+			return "INVALID_END_TIME"
+		}
+		end = t
+		if isDate {
+			end = t.AddDate(0, 0, 1)
+		}
+	}
+	if !end.After(start) {
+		return "END_BEFORE_START"
+	}
+	if end.Sub(start) > maxEventDays*24*time.Hour {
+		return "TOO_LONG_EVENT"
+	}
+
+	storageLoc := getTimeNow().Location()
+	anniversary.Start_time = start.In(storageLoc).Format(chatTimeLayout)
+	anniversary.End_time = end.In(storageLoc).Format(chatTimeLayout)
+	anniversary.Timezone = loc.String()
+	if !anniversary.Is_lunar {
+		local := start.In(loc)
+		anniversary.Year, anniversary.Month, anniversary.Date = local.Year(), int(local.Month()), local.Day()
+	}
+	return ""
+}
+
+// 반복 일정의 date(날짜) 일정 시작/끝 시간, 원래 일정과 같은 시각에 시작하고 길이도 같음
+func occurrenceTimes(anniversary model.AnniversaryData, start, end, date time.Time, loc *time.Location) (time.Time, time.Time) {
+	localStart := start.In(loc)
+	occurrenceStart := time.Date(date.Year(), date.Month(), date.Day(), localStart.Hour(), localStart.Minute(), localStart.Second(), 0, loc)
+	if anniversary.Is_all_day {
+		// 서머타임이 있는 시간대에서도 날짜 단위로 맞도록 일수로 더함
+		days := int(end.Sub(start).Round(24*time.Hour) / (24 * time.Hour))
+		return occurrenceStart, occurrenceStart.AddDate(0, 0, days)
+	}
+	return occurrenceStart, occurrenceStart.Add(end.Sub(start))
+}
+
+// 일정 한 번의 시작/끝 시간
+type eventOccurrence struct {
+	start time.Time
+	end time.Time
+}
+
+// 일정이 [from, to)와 겹치는 시작/끝 시간들, 반복 일정은 겹치는 날짜마다 하나씩
+func eventOccurrences(anniversary model.AnniversaryData, start, end time.Time, loc *time.Location, from, to time.Time) []eventOccurrence {
+	occurrences := []eventOccurrence{}
+	if anniversary.Recurrence == "" {
+		if start.Before(to) && end.After(from) {
+			occurrences = append(occurrences, eventOccurrence{start, end})
+		}
+		return occurrences
+	}
+
+	// 기간 시작 전에 시작해서 기간 안까지 이어지는 일정도 포함하도록 일정 길이만큼 앞의 달부터 확인
+	first := from.Add(-end.Sub(start)).In(loc)
+	last := to.In(loc)
+	for month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)); month = month.AddDate(0, 1, 0) {
+		for _, t := range occurrencesInMonth(anniversary, month.Year(), month.Month()) {
+			occurrenceStart, occurrenceEnd := occurrenceTimes(anniversary, start, end, t, loc)
+			if occurrenceStart.Before(to) && occurrenceEnd.After(from) {
+				occurrences = append(occurrences, eventOccurrence{occurrenceStart, occurrenceEnd})
+			}
+		}
+	}
+	return occurrences
+}
+
+// 저장된 일정들 중 [from, to)와 겹치는 일정, 반복 일정은 겹치는 날짜마다 하나씩 펼침
+func expandAnniversaries(anniversaries []model.AnniversaryData, from, to time.Time) []model.AnniversaryData {
+	expanded := []model.AnniversaryData{}
+	for _, anniversary := range anniversaries {
+		start, end, ok := eventTimes(anniversary)
+		if !ok {
+			continue
+		}
+		loc := eventLocation(anniversary)
+		for _, o := range eventOccurrences(anniversary, start, end, loc, from, to) {
+			occurrence := anniversary
+			if anniversary.Recurrence != "" || anniversary.Is_lunar {
+				occurrence.Origin_date = fmt.Sprintf("%04d-%02d-%02d", anniversary.Year, anniversary.Month, anniversary.Date)
+			}
+			formatEventTimes(&occurrence, o.start, o.end, anniversary.Is_all_day, loc)
+			expanded = append(expanded, occurrence)
+		}
+	}
+	return expanded
+}
+
+// 시작 시간순으로 정렬, 같은 날이면 하루 종일 일정이 먼저
+func sortAnniversaries(anniversaries []model.AnniversaryData) {
+	key := func(anniversary model.AnniversaryData) string {
+		date := fmt.Sprintf("%04d-%02d-%02d", anniversary.Year, anniversary.Month, anniversary.Date)
+		if anniversary.Is_all_day {
+			return date
+		}
+		return date + "T" + anniversary.Start_time
+	}
+	sort.SliceStable(anniversaries, func(i, j int) bool {
+		return key(anniversaries[i]) < key(anniversaries[j])
+	})
+}
+
+// 반복 일정의 from 이후(당일 포함) 가장 가까운 날짜, D-DAY 계산용
+func nextOccurrence(anniversary model.AnniversaryData, from time.Time) (time.Time, bool) {
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	today := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxNextOccurrenceMonths; i++ {
+		for _, t := range occurrencesInMonth(anniversary, month.Year(), month.Month()) {
+			if !t.Before(today) {
+				return t, true
+			}
+		}
+		month = month.AddDate(0, 1, 0)
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+)
+
+func joinDates(dates []time.Time) string {
+	formatted := []string{}
+	for _, date := range dates {
+		formatted = append(formatted, date.Format("2006-01-02"))
+	}
+	return strings.Join(formatted, ",")
+}
+
+func TestOccurrencesInMonth(t *testing.T) {
+	tests := []struct {
+		name string
+		anniversary model.AnniversaryData
+		year int
+		month time.Month
+		want string
+	}{
+		{"once", model.AnniversaryData{Year: 2024, Month: 3, Date: 14}, 2024, time.March, "2024-03-14"},
+		{"once other month", model.AnniversaryData{Year: 2024, Month: 3, Date: 14}, 2024, time.April, ""},
+		{"yearly", model.AnniversaryData{Year: 2020, Month: 5, Date: 1, Recurrence: "yearly"}, 2024, time.May, "2024-05-01"},
+		{"yearly before start", model.AnniversaryData{Year: 2025, Month: 5, Date: 1, Recurrence: "yearly"}, 2024, time.May, ""},
+		{"yearly leap day", model.AnniversaryData{Year: 2020, Month: 2, Date: 29, Recurrence: "yearly"}, 2023, time.February, "2023-02-28"},
+		{"monthly", model.AnniversaryData{Year: 2024, Month: 1, Date: 15, Recurrence: "monthly"}, 2024, time.June, "2024-06-15"},
+		{"monthly end of month", model.AnniversaryData{Year: 2024, Month: 1, Date: 31, Recurrence: "monthly"}, 2024, time.April, "2024-04-30"},
+		{"every 10 days", model.AnniversaryData{Year: 2024, Month: 1, Date: 1, Recurrence: "days", Interval_days: 10}, 2024, time.February, "2024-02-10,2024-02-20"},
+		{"every 7 days first month", model.AnniversaryData{Year: 2024, Month: 1, Date: 20, Recurrence: "days", Interval_days: 7}, 2024, time.January, "2024-01-20,2024-01-27"},
+		{"lunar new year", model.AnniversaryData{Year: 2000, Month: 1, Date: 1, Recurrence: "yearly", Is_lunar: true}, 2024, time.February, "2024-02-10"},
+		// 음력 12월은 다음 해 양력 1월이 될 수 있음
+		{"lunar december", model.AnniversaryData{Year: 2000, Month: 12, Date: 1, Recurrence: "yearly", Is_lunar: true}, 2024, time.January, "2024-01-11"},
+		{"invalid date", model.AnniversaryData{Year: 2024, Month: 2, Date: 30}, 2024, time.February, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinDates(occurrencesInMonth(tt.anniversary, tt.year, tt.month)); got != tt.want {
+				t.Errorf("occurrencesInMonth = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMilestonesInRange(t *testing.T) {
+	loc := time.FixedZone("KST", 9*60*60)
+	day := func(year int, month time.Month, date int) time.Time {
+		return time.Date(year, month, date, 0, 0, 0, 0, loc)
+	}
+	tests := []struct {
+		start_date string
+		from, to time.Time
+		want string
+	}{
+		// 사귄 날이 1일이라서 100일은 99일 뒤
+		{"2024/01/01", day(2024, 4, 1), day(2024, 5, 1), "2024-04-09 100일"},
+		{"2023/03/01", day(2023, 12, 1), day(2024, 3, 2), "2023-12-25 300일,2024-03-01 1주년"},
+		// to는 포함하지 않음
+		{"2024/01/01", day(2024, 4, 1), day(2024, 4, 9), ""},
+		// 2월 29일에 사귀었으면 평년에는 28일
+		{"2024/02/29", day(2025, 2, 1), day(2025, 3, 1), "2025-02-28 1주년"},
+		{"2024-01-01", day(2024, 1, 1), day(2025, 1, 1), ""},
+	}
+
+	for _, tt := range tests {
+		got := []string{}
+		for _, milestone := range milestonesInRange(1, tt.start_date, tt.from, tt.to, loc) {
+			if !milestone.Is_all_day {
+				t.Errorf("milestone %s is not all-day", milestone.Contents)
+			}
+			got = append(got, milestone.Start_time+" "+milestone.Contents)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("milestonesInRange(%q, %s, %s) = %q, want %q", tt.start_date, tt.from.Format("2006-01-02"), tt.to.Format("2006-01-02"), strings.Join(got, ","), tt.want)
+		}
+	}
+}
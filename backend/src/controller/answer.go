@@ -0,0 +1,396 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 두 사람이 모두 답했을 때 커플에게 보내는 websocket 이벤트
+// 채팅 배열과 구분할 수 있도록 type이 있는 객체로 보냄
+type answerRevealedEvent struct {
+	Type string `json:"type"`
+	Question_id int `json:"question_id"`
+	Question_contents string `json:"question_contents"`
+	First_answer string `json:"first_answer"`
+	Second_answer string `json:"second_answer"`
+	My_answer string `json:"my_answer"`
+	Partner_answer string `json:"partner_answer"`
+	Answer_date string `json:"answer_date"`
+}
+
+// 답변 유효성 검사, 저장할 답변과 에러 메세지 리턴
+func validateAnswer(question_id int, text string) (string, string) {
+	answer := strings.TrimSpace(text)
+	if question_id <= 0 {
+		return answer, "INVALID_QUESTION"
+	}
+	if answer == "" || answer == "not-written" {
+		return answer, "EMPTY_ANSWER"
+	}
+	if utf8.RuneCountInString(answer) > 255 {
+		return answer, "ANSWER_TOO_LONG"
+	}
+	return answer, ""
+}
+
+// 답변을 저장하고, 이번 답변으로 두 사람이 모두 답했으면 접속 중인 두 사람에게 답변 공개 이벤트 전송
+// 저장하지 못한 경우 이유를 리턴 (ANSWER_LOCKED, INVALID_OPTION)
+func saveAnswer(uuid string, conn_id, question_id int, answer, first_uuid, second_uuid string) (string, error) {
+	// 객관식 질문이면 보기 중 하나만 답할 수 있음
+	question, isExist, err := model.GetQuestionDataByQuestionID(question_id)
+	if err != nil {
+		return "", err
+	}
+	if !isExist {
+		return "ANSWER_LOCKED", nil
+	}
+	if len(question.Answer_options) > 0 && !containsString(question.Answer_options, answer) {
+		return "INVALID_OPTION", nil
+	}
+
+	answerData, isSaved, isRevealed, err := model.SaveAnswer(conn_id, question_id, first_uuid == uuid, answer)
+	if err != nil {
+		return "", err
+	}
+	if !isSaved {
+		return "ANSWER_LOCKED", nil
+	}
+	if !isRevealed {
+		return "", nil
+	}
+
+	event := answerRevealedEvent{
+		Type: "answer_revealed",
+		Question_id: question_id,
+		Question_contents: answerData.QuestionContents,
+		First_answer: answerData.FirstAnswer,
+		Second_answer: answerData.SecondAnswer,
+		Answer_date: answerData.AnswerDate,
+	}
+
+	mutex.Lock()
+	first_conn, second_conn := conns[first_uuid], conns[second_uuid]
+	mutex.Unlock()
+
+	if first_conn != nil {
+		event.My_answer, event.Partner_answer = answerData.FirstAnswer, answerData.SecondAnswer
+		err := first_conn.WriteJSON(event)
+		if err != nil {
+			fmt.Println("ERROR #248 : ", err.Error())
+		}
+	}
+	if second_conn != nil {
+		event.My_answer, event.Partner_answer = answerData.SecondAnswer, answerData.FirstAnswer
+		err := second_conn.WriteJSON(event)
+		if err != nil {
+			fmt.Println("ERROR #249 : ", err.Error())
+		}
+	}
+	return "", nil
+}
+
+func containsString(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// 답변 작성/수정, 상대가 답하기 전까지만 수정 가능
+func UpdateAnswerHandler(c *gin.Context) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	questionID, err2 := strconv.Atoi(c.Param("questionID"))
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var input struct {
+		Answer string `json:"answer"`
+	}
+	err3 := c.ShouldBindJSON(&input)
+	if err3 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	answer, message := validateAnswer(questionID, input.Answer)
+	if message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+
+	first_uuid, second_uuid, conn_id, err4 := model.GetConnectionByUsrsUUID(uuid)
+	if err4 != nil {
+		fmt.Println("ERROR #250 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	message, err5 := saveAnswer(uuid, conn_id, questionID, answer, first_uuid, second_uuid)
+	if err5 != nil {
+		fmt.Println("ERROR #251 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// 받은 적 없는 질문이거나 상대가 이미 답해서 수정할 수 없는 경우
+	if message == "ANSWER_LOCKED" {
+		c.String(http.StatusConflict, "%v", message)
+		return
+	}
+	if message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 내가 받은 질문과 지금까지 작성한 내 답변, 아직 수정 가능한지 여부
+func GetMyAnswerHandler(c *gin.Context) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	questionID, err2 := strconv.Atoi(c.Param("questionID"))
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	first_uuid, _, conn_id, err3 := model.GetConnectionByUsrsUUID(uuid)
+	if err3 != nil {
+		fmt.Println("ERROR #252 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	answerData, isExist, err4 := model.GetAnswerByConnIDandQuestionID(conn_id, questionID)
+	if err4 != nil {
+		fmt.Println("ERROR #253 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	myAnswer, partnerAnswer := answerData.SecondAnswer, answerData.FirstAnswer
+	if first_uuid == uuid {
+		myAnswer, partnerAnswer = answerData.FirstAnswer, answerData.SecondAnswer
+	}
+	sendData := struct {
+		Question_id int `json:"question_id"`
+		Question_contents string `json:"question_contents"`
+		Answer_options []string `json:"answer_options,omitempty"`
+		My_answer string `json:"my_answer"`
+		Partner_answered bool `json:"partner_answered"`
+		Editable bool `json:"editable"`
+	}{
+		questionID,
+		answerData.QuestionContents,
+		answerData.Answer_options,
+		myAnswer,
+		partnerAnswer != "not-written",
+		partnerAnswer == "not-written",
+	}
+
+	marshaledData, err5 := json.Marshal(sendData)
+	if err5 != nil {
+		fmt.Println("ERROR #254 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 답변 모아보기 항목, 객관식 질문이면 두 사람이 같은 보기를 골랐는지 is_match로 표시
+type archivedAnswer struct {
+	Question_id int `json:"question_id"`
+	Question_contents string `json:"question_contents"`
+	Category string `json:"category"`
+	Answer_options []string `json:"answer_options,omitempty"`
+	First_answer string `json:"first_answer"`
+	Second_answer string `json:"second_answer"`
+	My_answer string `json:"my_answer"`
+	Partner_answer string `json:"partner_answer"`
+	Answer_date string `json:"answer_date"`
+	Is_match *bool `json:"is_match,omitempty"`
+}
+
+// category, from, to(YYYY-MM-DD) 파라미터로 답변 조회 조건 생성
+func getAnswerFilter(c *gin.Context) (model.AnswerFilter, bool) {
+	filter := model.AnswerFilter{
+		Category: strings.ToLower(c.Query("category")),
+		From: c.Query("from"),
+		To: c.Query("to"),
+	}
+	if (filter.From != "" && !dateRegexp.MatchString(filter.From)) || (filter.To != "" && !dateRegexp.MatchString(filter.To)) {
+		return filter, false
+	}
+	return filter, true
+}
+
+// 두 사람이 모두 답한 답변 모아보기 (?page=&limit=&category=&from=&to=)
+// ?format=csv면 조건에 맞는 전체 답변을 CSV 파일로 내려받음
+func GetAnswerArchiveHandler(c *gin.Context) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	filter, ok := getAnswerFilter(c)
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	isCSV := c.Query("format") == "csv"
+	if !isCSV {
+		filter.Limit, filter.Offset = getPageParams(c)
+	}
+
+	first_uuid, _, conn_id, err2 := model.GetConnectionByUsrsUUID(uuid)
+	if err2 != nil {
+		fmt.Println("ERROR #260 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	answerDatas, total, err3 := model.GetAnswerArchive(conn_id, filter)
+	if err3 != nil {
+		fmt.Println("ERROR #261 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	answers := []archivedAnswer{}
+	for _, answerData := range answerDatas {
+		answer := archivedAnswer{
+			Question_id: answerData.Question_id,
+			Question_contents: answerData.QuestionContents,
+			Category: answerData.Category,
+			Answer_options: answerData.Answer_options,
+			First_answer: answerData.FirstAnswer,
+			Second_answer: answerData.SecondAnswer,
+			My_answer: answerData.SecondAnswer,
+			Partner_answer: answerData.FirstAnswer,
+			Answer_date: answerData.AnswerDate,
+		}
+		if first_uuid == uuid {
+			answer.My_answer, answer.Partner_answer = answerData.FirstAnswer, answerData.SecondAnswer
+		}
+		if len(answerData.Answer_options) > 0 {
+			isMatch := answerData.FirstAnswer == answerData.SecondAnswer
+			answer.Is_match = &isMatch
+		}
+		answers = append(answers, answer)
+	}
+
+	if isCSV {
+		c.Header("Content-Disposition", `attachment; filename="answers.csv"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		// 엑셀에서 한글이 깨지지 않도록 BOM 추가
+		c.Writer.Write([]byte("\xef\xbb\xbf"))
+		writer := csv.NewWriter(c.Writer)
+		writer.Write([]string{"answer_date", "category", "question", "my_answer", "partner_answer"})
+		for _, answer := range answers {
+			writer.Write([]string{answer.Answer_date, answer.Category, answer.Question_contents, answer.My_answer, answer.Partner_answer})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			fmt.Println("ERROR #262 : ", err.Error())
+		}
+		return
+	}
+
+	if total == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	sendData := struct {
+		Total int `json:"total"`
+		Answers []archivedAnswer `json:"answers"`
+	}{total, answers}
+
+	marshaledData, err4 := json.Marshal(sendData)
+	if err4 != nil {
+		fmt.Println("ERROR #263 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 답변 통계, 객관식 질문에서 두 사람이 같은 보기를 고른 비율을 전체/카테고리별로 계산 (?category=&from=&to=)
+func GetAnswerInsightsHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	filter, ok := getAnswerFilter(c)
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	insightDatas, err2 := model.GetAnswerInsights(conn_id, filter)
+	if err2 != nil {
+		fmt.Println("ERROR #264 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type insight struct {
+		model.AnswerInsightData
+		Match_rate float64 `json:"match_rate"`
+	}
+	matchRate := func(matching, choice int) float64 {
+		if choice == 0 {
+			return 0
+		}
+		return float64(matching) / float64(choice)
+	}
+
+	sendData := struct {
+		Answered_count int `json:"answered_count"`
+		Choice_count int `json:"choice_count"`
+		Matching_count int `json:"matching_count"`
+		Match_rate float64 `json:"match_rate"`
+		Categories []insight `json:"categories"`
+	}{Categories: []insight{}}
+	for _, insightData := range insightDatas {
+		sendData.Answered_count += insightData.Answered_count
+		sendData.Choice_count += insightData.Choice_count
+		sendData.Matching_count += insightData.Matching_count
+		sendData.Categories = append(sendData.Categories, insight{insightData, matchRate(insightData.Matching_count, insightData.Choice_count)})
+	}
+	sendData.Match_rate = matchRate(sendData.Matching_count, sendData.Choice_count)
+
+	marshaledData, err3 := json.Marshal(sendData)
+	if err3 != nil {
+		fmt.Println("ERROR #265 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 기간 조회에서 받는 시간 형식, 날짜만 보내면 하루 전체를 의미
+var rangeTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	time.RFC3339,
+}
+
+// 달력 요약에서 한 번에 조회할 수 있는 최대 일수
+const maxCalendarDays = 366
+
+// tz 파라미터(IANA 시간대 이름, 기본 Asia/Seoul)를 location으로 변환
+func getTimezoneParam(c *gin.Context) (*time.Location, bool) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return getTimeNow().Location(), true
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
+
+// 기간 조회 시간 파싱, 날짜만 있는 경우 isDate가 true
+// 시간대가 포함된 RFC3339 형식이 아니면 loc 기준 시간으로 해석
+func parseRangeTime(value string, loc *time.Location) (time.Time, bool, bool) {
+	if dateRegexp.MatchString(value) {
+		t, err := time.ParseInLocation("2006-01-02", value, loc)
+		return t, true, err == nil
+	}
+	for _, layout := range rangeTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, true
+		}
+	}
+	return time.Time{}, false, false
+}
+
+// from, to, tz 파라미터로 [from, to) 기간을 계산, to는 보낸 값까지 포함하도록 변환
+// to가 없으면 from이 날짜일 땐 그 날 하루, 시간일 땐 현재까지
+func getRangeParams(c *gin.Context) (time.Time, time.Time, *time.Location, bool) {
+	loc, ok := getTimezoneParam(c)
+	if !ok {
+		return time.Time{}, time.Time{}, nil, false
+	}
+
+	from, fromIsDate, ok := parseRangeTime(c.Query("from"), loc)
+	if !ok {
+		return time.Time{}, time.Time{}, nil, false
+	}
+
+	var to time.Time
+	if c.Query("to") == "" {
+		if fromIsDate {
+			to = from.AddDate(0, 0, 1)
+		} else {
+			to = getTimeNow().Add(time.Second)
+		}
+	} else {
+		t, toIsDate, ok := parseRangeTime(c.Query("to"), loc)
+		if !ok {
+			return time.Time{}, time.Time{}, nil, false
+		}
+		if toIsDate {
+			to = t.AddDate(0, 0, 1)
+		} else {
+			to = t.Add(time.Second)
+		}
+	}
+
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, nil, false
+	}
+	return from, to, loc, true
+}
+
+// DB에 저장된 시간(Asia/Seoul)을 loc 기준으로 변환, 형식이 다른 예전 데이터는 그대로 둠
+func convertWriteTime(writeTime string, loc *time.Location) string {
+	t, err := time.ParseInLocation(chatTimeLayout, writeTime, getTimeNow().Location())
+	if err != nil {
+		return writeTime
+	}
+	return t.In(loc).Format(chatTimeLayout)
+}
+
+// 기간 안에 작성된 채팅을 오래된 순으로 리턴, 일정 알림 같은 시스템 메시지 포함 (?from=&to=&tz=&page=&limit=)
+func GetChatRangeHandler(c *gin.Context) {
+	coupleUUIDs, ok := getCoupleWriterIDs(c, true)
+	if !ok {
+		return
+	}
+
+	from, to, loc, ok := getRangeParams(c)
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	limit, offset := getPageParams(c)
+
+	storageLoc := getTimeNow().Location()
+	chats, total, err := model.GetChatsInRange(coupleUUIDs, from.In(storageLoc).Format(chatTimeLayout), to.In(storageLoc).Format(chatTimeLayout), limit, offset)
+	if err != nil {
+		fmt.Println("ERROR #220 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(chats) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for i := range chats {
+		chats[i].Write_time = convertWriteTime(chats[i].Write_time, loc)
+	}
+
+	sendData := struct {
+		Total int `json:"total"`
+		Chats []model.ChatData `json:"chats"`
+	}{total, chats}
+
+	marshaledData, err2 := json.Marshal(sendData)
+	if err2 != nil {
+		fmt.Println("ERROR #221 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+type dayCount struct {
+	Date string `json:"date"`
+	Count int `json:"count"`
+}
+
+// 기간 안의 날짜별 채팅 개수 리턴, 달력 히트맵용 (?from=&to=&tz=)
+// 채팅이 없는 날도 count 0으로 포함
+func GetChatCalendarHandler(c *gin.Context) {
+	coupleUUIDs, ok := getCoupleUUIDs(c)
+	if !ok {
+		return
+	}
+
+	from, to, loc, ok := getRangeParams(c)
+	if !ok || to.Sub(from) > maxCalendarDays*24*time.Hour {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	storageLoc := getTimeNow().Location()
+	hourlyCounts, err := model.GetHourlyChatCounts(coupleUUIDs, from.In(storageLoc).Format(chatTimeLayout), to.In(storageLoc).Format(chatTimeLayout))
+	if err != nil {
+		fmt.Println("ERROR #222 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// 시간 단위 개수를 요청한 시간대의 날짜로 변환해서 합산
+	counts := make(map[string]int)
+	for hour, count := range hourlyCounts {
+		t, err := time.ParseInLocation(chatTimeLayout, hour, storageLoc)
+		if err != nil {
+			continue
+		}
+		counts[t.In(loc).Format("2006-01-02")] += count
+	}
+
+	sendData := []dayCount{}
+	for day := from.In(loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		sendData = append(sendData, dayCount{date, counts[date]})
+	}
+
+	marshaledData, err2 := json.Marshal(sendData)
+	if err2 != nil {
+		fmt.Println("ERROR #223 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// year, month, date 파라미터로 그 날의 첫 채팅을 찾음, 없으면 빈 slice
+func getFirstChatOfDate(coupleUUIDs []string, year, month, date string) ([]model.ChatData, error) {
+	yearNum, err1 := strconv.Atoi(year)
+	monthNum, err2 := strconv.Atoi(month)
+	dateNum, err3 := strconv.Atoi(date)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, nil
+	}
+
+	from := time.Date(yearNum, time.Month(monthNum), dateNum, 0, 0, 0, 0, getTimeNow().Location())
+	to := from.AddDate(0, 0, 1)
+	chats, _, err := model.GetChatsInRange(coupleUUIDs, from.Format(chatTimeLayout), to.Format(chatTimeLayout), 1, 0)
+	return chats, err
+}
@@ -0,0 +1,489 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// 업로드 중인 조각 파일을 모아두는 디렉토리
+const uploadDir = "assets/uploads"
+
+// 조각 하나의 기본 최대 크기 (MB), UPLOAD_CHUNK_MB 환경변수로 변경 가능
+const defaultChunkMB = 8
+
+// 이 시간(시간 단위) 동안 조각이 들어오지 않은 업로드는 삭제, UPLOAD_EXPIRE_HOURS 환경변수로 변경 가능
+const defaultUploadExpireHours = 24
+
+// 같은 업로드에 조각이 동시에 들어와서 파일이 꼬이지 않도록 업로드별로 잠금. KEY = upload_id, VALUE = *uploadLock
+// 잠금을 가지고 있거나 기다리는 요청이 모두 끝나야 map에서 지움
+var uploadLocks = make(map[string]*uploadLock)
+var uploadLocksMutex = &sync.Mutex{}
+
+type uploadLock struct {
+	sync.Mutex
+	holders int
+}
+
+var checksumRegexp = regexp.MustCompile("^[0-9a-f]{64}$")
+
+func uploadPartPath(uploadID string) string {
+	return uploadDir + "/" + uploadID + ".part"
+}
+
+// 업로드 잠금을 얻고 잠금을 푸는 함수를 리턴
+// 기다리는 동안 다른 요청이 업로드를 완료하거나 취소했을 수 있으므로 잠금을 얻은 뒤에 업로드를 조회해야 함
+func lockUpload(uploadID string) func() {
+	uploadLocksMutex.Lock()
+	lock := uploadLocks[uploadID]
+	if lock == nil {
+		lock = &uploadLock{}
+		uploadLocks[uploadID] = lock
+	}
+	lock.holders++
+	uploadLocksMutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		uploadLocksMutex.Lock()
+		lock.holders--
+		if lock.holders == 0 {
+			delete(uploadLocks, uploadID)
+		}
+		uploadLocksMutex.Unlock()
+	}
+}
+
+func uploadExpireHours() int {
+	hours, err := strconv.Atoi(os.Getenv("UPLOAD_EXPIRE_HOURS"))
+	if err != nil || hours <= 0 {
+		return defaultUploadExpireHours
+	}
+	return hours
+}
+
+// 현재까지 받은 byte 수 = 조각 파일 크기
+func uploadedOffset(uploadID string) (int64, error) {
+	info, err := os.Stat(uploadPartPath(uploadID))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// cookie의 uuid가 만든 업로드인지 확인해서 업로드 정보를 리턴, 아니면 응답을 쓰고 false 리턴
+func getOwnUpload(c *gin.Context) (model.UploadData, bool) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return model.UploadData{}, false
+	}
+
+	uploadData, isExist, err2 := model.GetUploadByUploadID(c.Param("uploadID"))
+	if err2 != nil {
+		fmt.Println("ERROR #153 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return uploadData, false
+	}
+	if !isExist || uploadData.UUID != uuid {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return uploadData, false
+	}
+	return uploadData, true
+}
+
+func writeUploadStatus(c *gin.Context, uploadData model.UploadData, offset int64) {
+	sendData := struct {
+		model.UploadData
+		Offset int64 `json:"offset"`
+	}{
+		uploadData,
+		offset,
+	}
+
+	marshaledData, err := json.Marshal(sendData)
+	if err != nil {
+		fmt.Println("ERROR #154 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 분할 업로드 시작, 파일 이름/크기/sha256을 받아서 upload_id 발급
+func InitiateUploadHandler(c *gin.Context) {
+	uuidString, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	conn_id, err1 := model.SelectConnIDByUUID(uuidString)
+	if err1 != nil {
+		fmt.Println("ERROR #155 : ", err1.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var uploadData model.UploadData
+	err2 := c.ShouldBindJSON(&uploadData)
+	if err2 != nil {
+		fmt.Println("ERROR #156 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	uploadData.Checksum = strings.ToLower(uploadData.Checksum)
+	if uploadData.File_size <= 0 || !checksumRegexp.MatchString(uploadData.Checksum) {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if uploadData.File_size > maxUploadSizeLimit() {
+		c.String(http.StatusRequestEntityTooLarge, "%v", "FILE_TOO_LARGE")
+		return
+	}
+
+	// 종류는 완료 시점에 내용으로 판별하고, 여기서는 저장공간만 미리 확인
+	usedBytes, _, err3 := model.GetStorageUsageByConnID(conn_id)
+	if err3 != nil {
+		fmt.Println("ERROR #157 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if usedBytes+uploadData.File_size > storageQuota() {
+		c.String(http.StatusRequestEntityTooLarge, "%v", "QUOTA_EXCEEDED")
+		return
+	}
+
+	uploadData.Upload_id = uuid.New().String()
+	uploadData.UUID = uuidString
+	uploadData.Connection_id = conn_id
+	uploadData.File_name = sanitizeFileName(uploadData.File_name)
+
+	err4 := os.MkdirAll(uploadDir, 0755)
+	if err4 != nil {
+		fmt.Println("ERROR #158 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	err5 := model.InsertUpload(uploadData)
+	if err5 != nil {
+		fmt.Println("ERROR #159 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeUploadStatus(c, uploadData, 0)
+}
+
+// 업로드 진행상황 불러오기, 연결이 끊겼던 클라이언트는 offset부터 이어서 전송
+func GetUploadHandler(c *gin.Context) {
+	uploadData, ok := getOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	offset, err := uploadedOffset(uploadData.Upload_id)
+	if err != nil {
+		fmt.Println("ERROR #160 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeUploadStatus(c, uploadData, offset)
+}
+
+// 조각 업로드, ?offset=은 이 조각이 시작하는 위치이고 지금까지 받은 크기와 같아야 함
+func UploadPartHandler(c *gin.Context) {
+	unlock := lockUpload(c.Param("uploadID"))
+	defer unlock()
+
+	uploadData, ok := getOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	current, err1 := uploadedOffset(uploadData.Upload_id)
+	if err1 != nil {
+		fmt.Println("ERROR #161 : ", err1.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// 이미 받은 조각을 다시 보냈거나 중간 조각이 빠진 경우, 현재 offset을 알려줘서 이어서 보내게 함
+	if offset != current {
+		c.Writer.WriteHeader(http.StatusConflict)
+		writeUploadStatus(c, uploadData, current)
+		return
+	}
+
+	chunkLimit := envMB("UPLOAD_CHUNK_MB", defaultChunkMB)
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, chunkLimit)
+
+	file, err2 := os.OpenFile(uploadPartPath(uploadData.Upload_id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err2 != nil {
+		fmt.Println("ERROR #162 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// 선언한 파일 크기를 넘는 부분은 받지 않음
+	remain := uploadData.File_size - current
+	written, err3 := io.Copy(file, io.LimitReader(body, remain+1))
+	if written > remain {
+		file.Truncate(current)
+		c.String(http.StatusRequestEntityTooLarge, "%v", "EXCEED_FILE_SIZE")
+		return
+	}
+	if err3 != nil {
+		// 끊기기 전까지 받은 부분은 그대로 두고, 클라이언트는 GET으로 offset을 확인해서 이어서 보냄
+		fmt.Println("ERROR #163 : ", err3.Error())
+		if errors.As(err3, new(*http.MaxBytesError)) {
+			file.Truncate(current)
+			c.String(http.StatusRequestEntityTooLarge, "%v", "CHUNK_TOO_LARGE")
+			return
+		}
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err4 := model.TouchUploadByUploadID(uploadData.Upload_id)
+	if err4 != nil {
+		fmt.Println("ERROR #164 : ", err4.Error())
+	}
+
+	writeUploadStatus(c, uploadData, current+written)
+}
+
+// 업로드 완료, 크기와 sha256을 검증한 뒤 일반 파일 채팅과 같은 방식으로 저장
+func CompleteUploadHandler(c *gin.Context) {
+	unlock := lockUpload(c.Param("uploadID"))
+	defer unlock()
+
+	uploadData, ok := getOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	partPath := uploadPartPath(uploadData.Upload_id)
+	file, err := os.Open(partPath)
+	if err != nil {
+		fmt.Println("ERROR #165 : ", err.Error())
+		c.String(http.StatusBadRequest, "%v", "INCOMPLETE")
+		return
+	}
+
+	hash := sha256.New()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+	hash.Write(head)
+	size, err1 := io.Copy(hash, file)
+	file.Close()
+	if err1 != nil {
+		fmt.Println("ERROR #166 : ", err1.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	size += int64(n)
+
+	if size != uploadData.File_size {
+		c.String(http.StatusBadRequest, "%v", "INCOMPLETE")
+		return
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != uploadData.Checksum {
+		// 내용이 깨진 파일은 처음부터 다시 받아야 하므로 조각 파일 삭제
+		os.Remove(partPath)
+		c.String(http.StatusUnprocessableEntity, "%v", "CHECKSUM_MISMATCH")
+		return
+	}
+
+	mimeType, status, message, err2 := checkUploadAllowed(uploadData.Connection_id, head, size)
+	if err2 != nil {
+		fmt.Println("ERROR #167 : ", err2.Error())
+	}
+	if status != http.StatusOK {
+		removeUpload(uploadData.Upload_id)
+		c.String(status, "%v", message)
+		return
+	}
+
+	// 이미지는 위치정보를 지운 내용을 임시 파일로 저장, 나머지는 조각 파일을 그대로 이동
+	// 파일을 먼저 준비하고 레코드를 저장해야 실패했을 때 파일 없는 채팅이 남지 않음
+	isImage := mimeCategory(mimeType) == "image"
+	srcPath := partPath
+	var data []byte
+	if isImage {
+		var err4 error
+		data, err4 = os.ReadFile(partPath)
+		if err4 != nil {
+			fmt.Println("ERROR #169 : ", err4.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data = stripLocationMetadata(data)
+		size = int64(len(data))
+
+		var err5 error
+		srcPath, err5 = writeTempUpload(bytes.NewReader(data))
+		if err5 != nil {
+			fmt.Println("ERROR #170 : ", err5.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	chatID, filePath, isSaved, err3 := insertFileChat(uploadData.UUID, uploadData.Connection_id, uploadData.File_name, mimeType, size)
+	if err3 != nil {
+		fmt.Println("ERROR #168 : ", err3.Error())
+		if srcPath != partPath {
+			os.Remove(srcPath)
+		}
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isSaved {
+		if srcPath != partPath {
+			os.Remove(srcPath)
+		}
+		removeUpload(uploadData.Upload_id)
+		c.String(http.StatusRequestEntityTooLarge, "%v", "QUOTA_EXCEEDED")
+		return
+	}
+
+	err7 := moveUploadedFile(srcPath, filePath, chatID)
+	if err7 != nil {
+		fmt.Println("ERROR #171 : ", err7.Error())
+		if srcPath != partPath {
+			os.Remove(srcPath)
+		}
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if isImage {
+		err6 := generateThumbnails(chatID, data)
+		if err6 != nil {
+			fmt.Println("ERROR #144 : ", err6.Error())
+		}
+	}
+	removeUpload(uploadData.Upload_id)
+
+	sendData := struct {
+		ChatID int `json:"chat_id"`
+	}{
+		chatID,
+	}
+	marshaledData, err8 := json.Marshal(sendData)
+	if err8 != nil {
+		fmt.Println("ERROR #172 : ", err8.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 업로드 취소
+func AbortUploadHandler(c *gin.Context) {
+	unlock := lockUpload(c.Param("uploadID"))
+	defer unlock()
+
+	uploadData, ok := getOwnUpload(c)
+	if !ok {
+		return
+	}
+
+	err := removeUpload(uploadData.Upload_id)
+	if err != nil {
+		fmt.Println("ERROR #173 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 업로드 레코드와 조각 파일 삭제
+func removeUpload(uploadID string) error {
+	err := os.Remove(uploadPartPath(uploadID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return model.DeleteUploadByUploadID(uploadID)
+}
+
+// 오래 방치된 업로드를 주기적으로 정리
+func StartUploadCleaner() {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for ; true; <-ticker.C {
+			cleanExpiredUploads()
+		}
+	}()
+}
+
+func cleanExpiredUploads() {
+	hours := uploadExpireHours()
+
+	uploadIDs, err := model.GetExpiredUploadIDs(hours)
+	if err != nil {
+		fmt.Println("ERROR #174 : ", err.Error())
+		return
+	}
+	for _, uploadID := range uploadIDs {
+		unlock := lockUpload(uploadID)
+		err := removeUpload(uploadID)
+		unlock()
+		if err != nil {
+			fmt.Println("ERROR #175 : ", err.Error())
+		}
+	}
+
+	// 커넥션 삭제 등으로 레코드 없이 남은 조각 파일 정리
+	expireTime := time.Now().Add(-time.Duration(hours) * time.Hour)
+	err2 := filepath.Walk(uploadDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() && info.ModTime().Before(expireTime) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err2 != nil {
+		fmt.Println("ERROR #176 : ", err2.Error())
+	}
+}
@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -21,7 +23,26 @@ import (
 )
 
 // 모든 클라이언트와 서버 간의 connection을 저장하는 map. KEY = uuid, VALUE = conn
-var conns = make(map[string]*websocket.Conn)
+var conns = make(map[string]*wsConn)
+
+// 채팅, 질문, 알림을 보내는 goroutine들이 한 커넥션에 동시에 쓰면 gorilla/websocket이 panic을 일으키므로
+// 커넥션마다 쓰기 잠금을 두고 모든 write를 이 타입을 통해서 함
+type wsConn struct {
+	*websocket.Conn
+	writeMutex sync.Mutex
+}
+
+func (c *wsConn) WriteJSON(v interface{}) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return c.Conn.WriteJSON(v)
+}
+
+func (c *wsConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return c.Conn.WriteMessage(messageType, data)
+}
 
 // 커넥션을 끊으면 작동하는 timer를 저장하는 map. KEY = connection_id, VALUE = timer
 var timerMap  = make(map[int]*time.Timer)
@@ -42,6 +63,9 @@ func UnConnectDB() {
 	}
 }
 
+// chat.write_time 등 DB에 저장하는 시간 형식, 시간대는 getTimeNow와 같은 Asia/Seoul
+const chatTimeLayout = "2006-01-02 15:04:05"
+
 // 서버 시간대를 클라이언트/DB와 일치시키기 위해 location 설정
 func getTimeNow() time.Time {
 	loc, err := time.LoadLocation("Asia/Seoul")
@@ -287,23 +311,10 @@ func setConnDeleteTimer(uuid string, connection_id int) {
 		}
 
 		// 서버에 저장되어있던 채팅 파일 삭제
-		chatDatas, _ := model.SelectChatByUsrsUUID(first_usr, second_usr)
+		chatDatas, _ := model.SelectChatByUsrsUUID(first_usr, second_usr, conn_id)
 		for _, v := range chatDatas {
 			if v.Is_file == 1 {
-				err := filepath.Walk("assets", func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if !info.IsDir() {
-						if strings.Contains(info.Name(), strconv.Itoa(v.Chat_id)+"-") {
-							err := os.Remove(path)
-							if err != nil {
-								return err
-							}
-						}
-					}
-					return nil
-				})
+				err := removeAssetsByChatID(v.Chat_id)
 				if err != nil {
 					fmt.Println("ERROR #140 : ", err.Error())
 				}
@@ -567,11 +578,12 @@ func UpgradeHandler(c *gin.Context){
 		    },
 	}
 
-	conn, err1 := upgrader.Upgrade(c.Writer, c.Request, nil)
+	upgradedConn, err1 := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err1 != nil {
 		fmt.Println("ERROR #34 : ", err1.Error())
 		return
 	}
+	conn := &wsConn{Conn: upgradedConn}
 	defer conn.Close()
 	defer func(){
 		mutex.Lock()
@@ -603,7 +615,7 @@ func UpgradeHandler(c *gin.Context){
 		return
 	}
 
-	initialChats, err4 := model.SelectChatByUsrsUUID(first_uuid, second_uuid)
+	initialChats, err4 := model.SelectChatByUsrsUUID(first_uuid, second_uuid, conn_id)
 	if err4 != nil {
 		fmt.Println("ERROR #37 : ", err4.Error())
 		return 
@@ -617,39 +629,15 @@ func UpgradeHandler(c *gin.Context){
 		}
 	}
 
-	// 이전에 대답 안하고 커넥션 종료된 question 있는지 확인
-	order, err6 := model.GetUsrOrderByUUID(uuid)
-	if err6 != nil {
-		fmt.Println("ERROR #55 : ", err6.Error())
-		return
-	}
-
-	question_id, err7 := model.QuestionIDOfEmptyAnswerByOrder(order, conn_id)
+	// 이전에 대답 안하고 커넥션 종료된 question들을 받은 순서대로 전달
+	pendingQuestions, err7 := model.GetPendingQuestions(conn_id, uuid, first_uuid == uuid, getTimeNow().Format(chatTimeLayout), false)
 	if err7 != nil {
 		fmt.Println("ERROR #79 : ", err7.Error())
 		return
 	}
 
-	if question_id != 0 {
-		_, questionContents, err8 := model.GetQuestionByQuestionID(question_id)
-		if err8 != nil {
-			fmt.Println("ERROR #80 : ", err8.Error())
-			return
-		}
-
-		questiondata := model.ChatData{
-			Text_body: questionContents,
-			Writer_id: "question",
-			Write_time: time.Now().Format("2006/01/02 03:04"),
-			Is_answer: 1,
-			Is_deleted: 0,
-			Is_file: 0,
-			Chat_id: 0,
-			Question_id: question_id,
-		}
-		questiondatas := []model.ChatData{}
-		questiondatas = append(questiondatas, questiondata)
-		err := conn.WriteJSON(questiondatas)
+	for _, pendingQuestion := range pendingQuestions {
+		err := conn.WriteJSON(questionChat(pendingQuestion.Question_id, pendingQuestion.Question_contents, pendingQuestion.Answer_options))
 		if err != nil {
 			fmt.Println("ERROR #56 : ", err.Error())
 			return
@@ -677,33 +665,47 @@ func UpgradeHandler(c *gin.Context){
 			fmt.Println("ERROR #39 : ", err.Error())
 			break;
 		}
+		if len(chatData) == 0 {
+			continue
+		}
+
+		// 클라이언트마다 보내는 시간 형식이 달라서 작성 시간은 서버 시간으로 통일
+		chatData[0].Write_time = getTimeNow().Format(chatTimeLayout)
 
 		// 일반채팅이면 chat table에 저장, question에 대한 답이면 answer table에 저장
 		if chatData[0].Is_answer == 1 {
-			recieveAnswer(uuid, conn_id, chatData, first_uuid)
+			recieveAnswer(uuid, conn_id, chatData, first_uuid, second_uuid)
 		} else if chatData[0].Is_deleted == 1 {
 			if chatData[0].Is_file == 1 {
-				err := filepath.Walk("assets", func(path string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if !info.IsDir() {
-						if strings.Contains(info.Name(), strconv.Itoa(chatData[0].Chat_id)+"-") {
-							err := os.Remove(path)
-							if err != nil {
-								return err
-							}
-						}
-					}
-					return nil
-				})
+				err := removeAssetsByChatID(chatData[0].Chat_id)
 				if err != nil {
 					fmt.Println("ERROR #138 : ", err.Error())
 				}
 			}
+			// 삭제할 채팅의 단어 횟수를 빼기 위해 삭제 전에 내용 확인
+			deletedChat, isExist, err2 := model.GetChatForWordCount(chatData[0].Chat_id)
+			if err2 != nil {
+				fmt.Println("ERROR #271 : ", err2.Error())
+			}
 			err = model.DeleteChatByChatID(chatData[0].Chat_id)
 			if err != nil {
 				fmt.Println("ERROR #95 : ", err.Error())
+			} else if isExist && (deletedChat.Writer_id == first_uuid || deletedChat.Writer_id == second_uuid) {
+				updateWordCounts(conn_id, deletedChat, -1)
+				err = model.DeleteChatSentiment(chatData[0].Chat_id, conn_id)
+				if err != nil {
+					fmt.Println("ERROR #292 : ", err.Error())
+				}
+			}
+			if chatData[0].Is_file == 1 {
+				err = model.DeleteAttachmentByChatID(chatData[0].Chat_id)
+				if err != nil {
+					fmt.Println("ERROR #152 : ", err.Error())
+				}
+				err = model.DeletePhotoByChatID(chatData[0].Chat_id)
+				if err != nil {
+					fmt.Println("ERROR #207 : ", err.Error())
+				}
 			}
 			
 		} else if chatData[0].Is_file != 1 {
@@ -711,6 +713,9 @@ func UpgradeHandler(c *gin.Context){
 			// 어차피 커넥션 당 메시지 하나씩 전송 받으니까 slice index는 0으로 설정
 			if err != nil {
 				fmt.Println("ERROR #40 : ", err.Error())
+			} else {
+				updateWordCounts(conn_id, model.ChatData{Writer_id: uuid, Text_body: chatData[0].Text_body, Write_time: chatData[0].Write_time}, 1)
+				recordSentiment(conn_id, model.ChatData{Chat_id: chat_id, Writer_id: uuid, Text_body: chatData[0].Text_body, Write_time: chatData[0].Write_time})
 			}
 			chatData[0].Chat_id = chat_id
 		} else {
@@ -724,9 +729,18 @@ func UpgradeHandler(c *gin.Context){
 				fmt.Println("ERROR #135 : ", err2.Error())
 			}
 			chatData[0].Text_body = text_body
+
+			// 음성 메시지면 말풍선을 그릴 수 있도록 재생 길이와 파형도 같이 전송
+			voiceData, err3 := model.GetVoiceByChatID(chatID)
+			if err3 != nil {
+				fmt.Println("ERROR #210 : ", err3.Error())
+			}
+			chatData[0].Is_voice = voiceData.Is_voice
+			chatData[0].Duration_ms = voiceData.Duration_ms
+			chatData[0].Waveform = voiceData.Waveform
 		}
 		
-		target_conn := []*websocket.Conn{}
+		target_conn := []*wsConn{}
 
 		mutex.Lock()
 		if conns[first_uuid] != nil && conns[second_uuid] != nil {
@@ -759,79 +773,26 @@ func UpgradeHandler(c *gin.Context){
 	}
 }
 
-func sendQuestion(chatData []model.ChatData, conn_id int, target_conn []*websocket.Conn){
-// 채팅 중 단어가 발견되면 단어 관련된 질문을 커플에게 던지는 기능
-	// 1. 단어를 먼저 다 뽑아서
-	var target_word, question_contents string
-	var question_id int
-	r, err := model.SelectQuetions()
-	defer r.Close()
-	if err != nil {
-		fmt.Println("ERROR #44 : ", err.Error())
-	}
-	for r.Next() {
-		// 2. 방금 READ한 채팅에 단어가 있는지 돌면서 확인
-		r.Scan(&target_word, &question_id, &question_contents)	
-		if strings.Contains(chatData[0].Text_body, target_word) {
-			// 3. 단어가 발견되면 이전에 답을 한 전적이 있는지 검색
-			isExist, err := model.CheckAnswerByConnIDandQuestionID(conn_id, question_id)
-			if err != nil {
-				fmt.Println("ERROR #45 : ", err.Error())
-				return
-			}
-
-			// 4. 단어도 발견됐고, 이전에 했던 질문도 아니면 질문 WRITE
-			if !isExist {
-				questiondata := model.ChatData{
-					Text_body: question_contents,
-					Writer_id: "question",
-					Write_time: time.Now().Format("2006/01/02 03:04"),
-					Is_answer: 1,
-					Is_deleted: 0,
-					Is_file: 0,
-					Chat_id: 0,
-					Question_id: question_id,
-				}
-				questiondatas := []model.ChatData{}
-				questiondatas = append(questiondatas, questiondata)
-
-				for _, item := range target_conn {
-					err := item.WriteJSON(questiondatas)
-					if err != nil {
-						fmt.Println("ERROR #46 : ", err.Error())
-					}
-				}
-				// 5. answer에 답 적기 (는 위에 READ에서 처리)
-				err = model.InsertAnswer(chatData[0].Write_time, conn_id, question_id)
-				if err != nil {
-					fmt.Println("ERROR #42 : ", err.Error())
-				}
-			}
-		}
+// 질문에 대한 답변 저장, 커플에게 보낸 질문 중 상대가 아직 답하지 않았거나 내가 아직 답하지 않은 질문에만 저장됨
+func recieveAnswer(uuid string, conn_id int, chatData []model.ChatData, first_uuid, second_uuid string){
+	answer, message := validateAnswer(chatData[0].Question_id, chatData[0].Text_body)
+	if message != "" {
+		fmt.Println("ERROR #245 : ", message)
+		return
 	}
-}
 
-func recieveAnswer(uuid string, conn_id int, chatData []model.ChatData, first_uuid string){
-	isExist, err1 := model.CheckAnswerByConnIDandQuestionID(conn_id, chatData[0].Question_id)
-	if err1 != nil {
-		fmt.Println("ERROR #41 : ", err1.Error())
+	message, err := saveAnswer(uuid, conn_id, chatData[0].Question_id, answer, first_uuid, second_uuid)
+	if err != nil {
+		fmt.Println("ERROR #50 : ", err.Error())
 		return
 	}
-	
-	if isExist {
-		var err2 error
-		if first_uuid == uuid {
-			err2 = model.UpdateFirstAnswerByQuestionID(chatData[0].Text_body, chatData[0].Question_id)
-		} else {
-			err2 = model.UpdateSecondAnswerByQuestionID(chatData[0].Text_body, chatData[0].Question_id)
-		}
-		if err2 != nil {
-			fmt.Println("ERROR #50 : ", err2.Error())
-		}
+	if message != "" {
+		fmt.Println("ERROR #41 : ", message)
 	}
 }
 
-// 커넥션별로 채팅에서 가장 많이 사용된 단어 불러오기
+// 커넥션별로 채팅에서 가장 많이 사용된 단어 불러오기 (?window=day|week|month|all|custom&from=&to=)
+// 단어가 ranknum개보다 적으면 있는 만큼만 보냄, 두 사람이 같이 쓴 단어와 지난 주 대비 증감도 같이 보냄
 func GetMostUsedWordsHandler(c *gin.Context){
 	rankNumString := c.Param("ranknum")
 	uuid, err := model.CookieExist(c)
@@ -842,40 +803,80 @@ func GetMostUsedWordsHandler(c *gin.Context){
 	rankNumInt, err := strconv.Atoi(rankNumString)
 	if err != nil {
 		fmt.Println("ERROR #59 : ", err.Error())
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+		c.Writer.WriteHeader(http.StatusBadRequest)
 		return	
 	}
-	firstUUID, secondUUID, _, err := model.GetConnectionByUsrsUUID(uuid)
-	
-	var ohterFrequentWords []string
-	var err2 error
+	if rankNumInt < 1 || rankNumInt > maxRankNum {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	from, to, ok := getRankWindow(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "%v", "INVALID_WINDOW")
+		return
+	}
+	firstUUID, secondUUID, conn_id, err := model.GetConnectionByUsrsUUID(uuid)
+	if err != nil {
+		fmt.Println("ERROR #272 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	otherUUID := firstUUID
 	if firstUUID == uuid {
-		ohterFrequentWords, err2 = model.GetFrequentWords(secondUUID, rankNumInt)	
-		if err2 != nil {
-			fmt.Println("ERROR #80 : ", err2.Error())
-		}
-	} else {
-		ohterFrequentWords, err2 = model.GetFrequentWords(firstUUID, rankNumInt)
-		if err2 != nil {
-			fmt.Println("ERROR #80 : ", err2.Error())
-		}
+		otherUUID = secondUUID
 	}
-	myFrequentWords, err3 := model.GetFrequentWords(uuid, rankNumInt)
+
+	myFrequentWords, err2 := model.GetTopWords(conn_id, uuid, uuid, from, to, rankNumInt)
+	if err2 != nil {
+		fmt.Println("ERROR #80 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ohterFrequentWords, err3 := model.GetTopWords(conn_id, uuid, otherUUID, from, to, rankNumInt)
 	if err3 != nil {
-		fmt.Println("ERROR #80 : ", err3.Error())
+		fmt.Println("ERROR #273 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-
-	if myFrequentWords == nil || ohterFrequentWords == nil {
-		c.Writer.WriteHeader(http.StatusLengthRequired)
+	sharedWords, err4 := model.GetSharedWords(conn_id, uuid, from, to, rankNumInt)
+	if err4 != nil {
+		fmt.Println("ERROR #274 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	myTrends, err5 := getWordTrends(conn_id, uuid, uuid, myFrequentWords)
+	if err5 != nil {
+		fmt.Println("ERROR #275 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	otherTrends, err6 := getWordTrends(conn_id, uuid, otherUUID, ohterFrequentWords)
+	if err6 != nil {
+		fmt.Println("ERROR #276 : ", err6.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	sendData := struct {
+		From string `json:"from"`
+		To string `json:"to"`
 		MyWords []string `json:"mywords"`
 		OtherWords []string `json:"otherwords"`
+		MyCounts []model.WordCountData `json:"mycounts"`
+		OtherCounts []model.WordCountData `json:"othercounts"`
+		SharedWords []model.SharedWordData `json:"sharedwords"`
+		MyTrends []wordTrend `json:"mytrends"`
+		OtherTrends []wordTrend `json:"othertrends"`
 	}{
+		from,
+		to,
+		wordsOf(myFrequentWords),
+		wordsOf(ohterFrequentWords),
 		myFrequentWords,
 		ohterFrequentWords,
+		sharedWords,
+		myTrends,
+		otherTrends,
 	}
 
 	marshaledData, err := json.Marshal(sendData)
@@ -888,33 +889,60 @@ func GetMostUsedWordsHandler(c *gin.Context){
 	c.Writer.Write(marshaledData)
 }
 
-// words ranking에서 제외된 단어 불러오기
+// words ranking에서 제외된 단어 불러오기, 커플 목록과 내 목록을 합쳐서 보냄
+// ?detail=1이면 단어마다 커플(couple)/나(me) 목록 구분도 같이 보냄
 func GetExceptWordsHandler(c *gin.Context){
-	conn_id, err := GetConnIDByCookie(c)
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	conn_id, err := model.SelectConnIDByUUID(uuid)
 	if err != nil {
 		fmt.Println("ERROR #124 : ",  err.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return 
 	}
 	
-	exceptWords, err2 := model.GetExceptWords(conn_id)
+	exceptWords, err2 := model.GetExceptWords(conn_id, uuid)
 	if err2 != nil {
 		fmt.Println("ERROR #62 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 	if len(exceptWords) == 0 {
 		c.Writer.WriteHeader(http.StatusNoContent)	
 		return
 	}
-	marshaledData, err3 := json.Marshal(exceptWords)
+
+	var sendData interface{} = exceptWords
+	if c.Query("detail") != "1" {
+		words := []string{}
+		for _, exceptWord := range exceptWords {
+			words = append(words, exceptWord.Except_word)
+		}
+		sendData = words
+	}
+
+	marshaledData, err3 := json.Marshal(sendData)
 	if err3 != nil {
 		fmt.Println("ERROR #81 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 	c.Writer.Write(marshaledData)
 }
 
 // words ranking에서 제외시킬 단어 추가
+// {"except_word": "..."}로 하나, {"except_words": [...]}로 여러 개를 한 번에 추가
+// scope가 "me"면 내 랭킹 화면에서만, 없거나 "couple"이면 두 사람 모두에게 적용
 func InsertExceptWordHandler(c *gin.Context){
-	conn_id, err := GetConnIDByCookie(c)
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	conn_id, err := model.SelectConnIDByUUID(uuid)
 	if err != nil {
 		fmt.Println("ERROR #125 : ",  err.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
@@ -923,39 +951,117 @@ func InsertExceptWordHandler(c *gin.Context){
 
 	Input := struct {
 		Except_word string `json:"except_word"`
+		Except_words []string `json:"except_words"`
+		Scope string `json:"scope"`
 	}{}
 	err1 := c.ShouldBindJSON(&Input)
 	if err1 != nil {
-		fmt.Println("ERROR #63 : ", err1.Error())
-	}
-	isExist, err2 := model.CheckWordAlreadyExcepted(conn_id, Input.Except_word)
-	if err2 != nil {
-		fmt.Println("ERROR #63 : ", err2.Error())
-	}
-	if isExist {
 		c.Writer.WriteHeader(http.StatusBadRequest)
 		return
-	} else {
-		err2 := model.InsertExceptWord(conn_id, Input.Except_word)
+	}
+
+	scopeUUID, ok := exceptWordScope(uuid, Input.Scope)
+	if !ok {
+		c.String(http.StatusBadRequest, "%v", "INVALID_SCOPE")
+		return
+	}
+
+	// 단어 하나만 추가하는 경우, 이미 제외된 단어면 400
+	if Input.Except_words == nil {
+		word, message := normalizeExceptWord(Input.Except_word)
+		if message != "" {
+			c.String(http.StatusBadRequest, "%v", message)
+			return
+		}
+		added, err2 := model.InsertExceptWords(conn_id, scopeUUID, []string{word})
 		if err2 != nil {
-		fmt.Println("ERROR #63 : ", err2.Error())
+			fmt.Println("ERROR #63 : ", err2.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(added) == 0 {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
 		}
 		c.Writer.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// 여러 개를 추가하는 경우, 잘못된 단어가 하나라도 있으면 아무것도 추가하지 않음
+	if len(Input.Except_words) == 0 || len(Input.Except_words) > maxExceptWordImport {
+		c.String(http.StatusBadRequest, "%v", "INVALID_WORD_COUNT")
+		return
+	}
+	words := []string{}
+	importErrors := []importError{}
+	for i, exceptWord := range Input.Except_words {
+		word, message := normalizeExceptWord(exceptWord)
+		if message != "" {
+			importErrors = append(importErrors, importError{i + 1, message})
+			continue
+		}
+		if !containsString(words, word) {
+			words = append(words, word)
+		}
+	}
+	if len(importErrors) > 0 {
+		marshaledData, err := json.Marshal(struct {
+			Errors []importError `json:"errors"`
+		}{importErrors})
+		if err != nil {
+			fmt.Println("ERROR #277 : ", err.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		c.Writer.Write(marshaledData)
+		return
+	}
+
+	added, err2 := model.InsertExceptWords(conn_id, scopeUUID, words)
+	if err2 != nil {
+		fmt.Println("ERROR #278 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	skipped := []string{}
+	for _, word := range words {
+		if !containsString(added, word) {
+			skipped = append(skipped, word)
+		}
 	}
+
+	sendData := struct {
+		Added []string `json:"added"`
+		Skipped []string `json:"skipped"`
+	}{added, skipped}
+
+	marshaledData, err3 := json.Marshal(sendData)
+	if err3 != nil {
+		fmt.Println("ERROR #279 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
 }
 
-// words ranking에서 제외시켰던 단어 취소
+// words ranking에서 제외시켰던 단어 취소, 커플 목록과 내 목록에서 모두 취소
 func DeleteExceptWordHandler(c *gin.Context){
 	cancleWord := c.Param("param")
 
-	conn_id, err := GetConnIDByCookie(c)
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	conn_id, err := model.SelectConnIDByUUID(uuid)
 	if err != nil {
 		fmt.Println("ERROR #126 : ",  err.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return 
 	}
 
-	err3 := model.CancleExceptWord(conn_id, cancleWord)
+	err3 := model.CancleExceptWord(conn_id, uuid, cancleWord)
 	if err3 != nil {
 		fmt.Println("ERROR #68 : ", err3.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
@@ -980,34 +1086,14 @@ func GetChatDateHandler(c *gin.Context) {
 		return
 	}
 
-	year := c.Query("year")
-	month := c.Query("month")
-	date := c.Query("date")
-	monthNum, _  := strconv.Atoi(month)
-	dateNum, _  := strconv.Atoi(date)
-	if monthNum < 10 {
-		month = "0"+month
-	}
-	if dateNum < 10 {
-		date = "0"+date
-	}
-
-	chats, err3 := model.SelectChatByUsrsUUID(first_uuid, second_uuid)
+	// 그 날 하루 기간에서 첫 채팅만 조회
+	sendData, err3 := getFirstChatOfDate([]string{first_uuid, second_uuid}, c.Query("year"), c.Query("month"), c.Query("date"))
 	if err3 != nil {
 		fmt.Println("ERROR #112 : ", err3.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	var sendData []model.ChatData
-
-	for i := 0; i < len(chats); i++ {
-		if strings.Contains(chats[i].Write_time, year+"-"+month+"-"+date) {
-			sendData = append(sendData, chats[i])
-			break;
-		}
-	}
-
 	if len(sendData) == 0 {
 		c.Writer.WriteHeader(http.StatusNoContent)
 		return
@@ -1023,44 +1109,40 @@ func GetChatDateHandler(c *gin.Context) {
 	c.Writer.Write(marshaledData)
 }
 
-// 검색한 단어가 포함된 채팅 리턴
+// 검색한 단어가 포함된 채팅 리턴, 클라이언트가 결과를 순서대로 이동하므로 오래된 순으로 전체 리턴
+// ?context=를 보내면 각 결과에 앞뒤 대화가 그 개수만큼 포함됨 (기본 0개)
 func GetChatWordHandler(c *gin.Context) {
-	targetWord := c.Param("param")
-
-	uuid, err1 := model.CookieExist(c)
-	if err1 != nil {
-		fmt.Println("ERROR #97 : ", err1.Error())
-		c.Writer.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	first_uuid, second_uuid, _, err2 := model.GetConnectionByUsrsUUID(uuid)
-	if err2 != nil {
-		fmt.Println("ERROR #98 : ", err2.Error())
-		c.Writer.WriteHeader(http.StatusInternalServerError)
+	filter, _, ok := buildSearchFilter(c, c.Param("param"))
+	if !ok {
 		return
 	}
+	filter.Chronological = true
+	filter.Limit, filter.Offset = maxWordSearchResults, 0
 
-	chats, err3 := model.SelectChatByUsrsUUID(first_uuid, second_uuid)
+	SearchChatSlice, _, err3 := model.SearchChats(filter)
 	if err3 != nil {
 		fmt.Println("ERROR #99 : ", err3.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	var SearchChatSlice []model.ChatData
-
-	for i := 0; i < len(chats); i++ {
-		if strings.Contains(chats[i].Text_body, targetWord) {
-			SearchChatSlice = append(SearchChatSlice, chats[i])
-		}
-	}
-
 	if len(SearchChatSlice) == 0 {
 		c.Writer.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	coupleUUIDs, ok := getCoupleUUIDs(c)
+	if !ok {
+		return
+	}
+	// 결과마다 쿼리를 한번씩 더 하므로 이 API는 ?context=를 보낸 경우에만 앞뒤 대화를 붙임
+	err5 := attachSearchContext(SearchChatSlice, coupleUUIDs, getSizeParam(c, "context", 0, maxContextSize))
+	if err5 != nil {
+		fmt.Println("ERROR #219 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	marshaledData, err4 := json.Marshal(SearchChatSlice)
 	if err4 != nil {
 		fmt.Println("ERROR #100 : ", err4.Error())
@@ -1090,6 +1172,21 @@ func InsertAnniversaryHandler(c *gin.Context) {
 	}
 
 	anniversaryData.Connection_id = conn_id
+	anniversaryData.Is_milestone = false
+	anniversaryData.Origin_date = ""
+	if message := normalizeEvent(&anniversaryData); message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+	if message := validateAnniversary(anniversaryData); message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+	reminders, message := normalizeReminders(anniversaryData.Reminders)
+	if message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
 
 	anniversary_id, err4 := model.GetDDayAnniversaryIDByConnID(conn_id)
 	if err4 != nil {
@@ -1107,15 +1204,26 @@ func InsertAnniversaryHandler(c *gin.Context) {
 		}	
 	}
 
-	err6 := model.InsertAnniversaryByConnID(anniversaryData)
+	new_anniversary_id, err6 := model.InsertAnniversaryByConnID(anniversaryData)
 	if err6 != nil {
 		fmt.Println("ERROR #106 : ", err6.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	if len(reminders) > 0 {
+		err7 := model.ReplaceReminders(conn_id, new_anniversary_id, reminders)
+		if err7 != nil {
+			fmt.Println("ERROR #314 : ", err7.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
 }
 
-// 저장된 캘린더 일정 중 해당 연도/달에 맞는 일정들 불러오기
+// 저장된 캘린더 일정 중 해당 연도/달(?year=&month=&tz=) 또는 기간(?from=&to=&tz=)과 겹치는 일정들 불러오기
+// 여러 날에 걸친 일정은 겹치는 모든 달에 포함, 반복 일정과 음력 일정은 기간 안의 양력 날짜로 펼침
+// 사귄 날부터 계산한 100일/1주년 같은 기념일도 같이 보냄 (?milestones=0이면 제외)
 func GetAnniversaryHandler(c *gin.Context){
 	conn_id, err := GetConnIDByCookie(c)
 	if err != nil {
@@ -1124,15 +1232,55 @@ func GetAnniversaryHandler(c *gin.Context){
 		return 
 	}
 
-	month := c.Query("month")
-	year := c.Query("year")
+	var from, to time.Time
+	var loc *time.Location
+	var ok bool
+	if c.Query("from") != "" {
+		from, to, loc, ok = getRangeParams(c)
+		ok = ok && to.Sub(from) <= maxAnniversaryRangeDays*24*time.Hour
+	} else {
+		loc, ok = getTimezoneParam(c)
+		month, err1 := strconv.Atoi(c.Query("month"))
+		year, err2 := strconv.Atoi(c.Query("year"))
+		ok = ok && err1 == nil && err2 == nil && month >= 1 && month <= 12
+		if ok {
+			from = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+			to = from.AddDate(0, 1, 0)
+		}
+	}
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-	anniversaryDatas, err3 := model.GetAnniversaryByConnIDAndMonthAndYear(conn_id, month, year)
+	storageLoc := getTimeNow().Location()
+	storedDatas, err3 := model.GetAnniversariesInRange(conn_id, from.In(storageLoc).Format(chatTimeLayout), to.In(storageLoc).Format(chatTimeLayout))
 	if err3 != nil {
 		fmt.Println("ERROR #107 : ", err3.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	reminders, err5 := model.GetRemindersByConnID(conn_id)
+	if err5 != nil {
+		fmt.Println("ERROR #315 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for i := range storedDatas {
+		storedDatas[i].Reminders = reminders[storedDatas[i].Anniversary_id]
+	}
+	anniversaryDatas := expandAnniversaries(storedDatas, from, to)
+
+	if c.Query("milestones") != "0" {
+		start_date, err := model.GetConnectionStartDate(conn_id)
+		if err != nil {
+			fmt.Println("ERROR #293 : ", err.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		anniversaryDatas = append(anniversaryDatas, milestonesInRange(conn_id, start_date, from, to, loc)...)
+	}
+	sortAnniversaries(anniversaryDatas)
 
 	if len(anniversaryDatas) == 0 {
 		c.Writer.WriteHeader(http.StatusNoContent)
@@ -1152,8 +1300,30 @@ func GetAnniversaryHandler(c *gin.Context){
 
 // 캘린더에서 일정 삭제
 func DeleteAnniversaryHandler(c *gin.Context) {
-	anniversary_id := c.Param("id")
-	err := model.DeleteAnniversaryByAnniversaryID(anniversary_id)
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	anniversary_id, err2 := strconv.Atoi(c.Param("id"))
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// 다른 커플의 일정은 지울 수 없음
+	isExist, err3 := model.CheckAnniversaryByConnID(conn_id, anniversary_id)
+	if err3 != nil {
+		fmt.Println("ERROR #317 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = model.DeleteAnniversaryByAnniversaryID(conn_id, anniversary_id)
 	if err != nil {
 		fmt.Println("ERROR #109 : ", err.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
@@ -1178,6 +1348,22 @@ func GetDDayHandler(c *gin.Context) {
 			return
 	}
 
+	// 반복 일정이나 음력 일정이면 오늘 이후 가장 가까운 날짜로 D-DAY를 계산하도록 바꿔서 보냄, 시간은 일정 시간대 기준
+	for i := range anniversaryData {
+		start, end, ok := eventTimes(anniversaryData[i])
+		if !ok {
+			continue
+		}
+		loc := eventLocation(anniversaryData[i])
+		if anniversaryData[i].Recurrence != "" || anniversaryData[i].Is_lunar {
+			if t, ok := nextOccurrence(anniversaryData[i], getTimeNow().In(loc)); ok {
+				anniversaryData[i].Origin_date = fmt.Sprintf("%04d-%02d-%02d", anniversaryData[i].Year, anniversaryData[i].Month, anniversaryData[i].Date)
+				start, end = occurrenceTimes(anniversaryData[i], start, end, t, loc)
+			}
+		}
+		formatEventTimes(&anniversaryData[i], start, end, anniversaryData[i].Is_all_day, loc)
+	}
+
 	if len(anniversaryData) == 0  {
 		c.Writer.WriteHeader(http.StatusNoContent)
 	} else {
@@ -1191,52 +1377,227 @@ func GetDDayHandler(c *gin.Context) {
 	}
 }
 
+// 채팅으로 보낸 파일 저장, 이미지는 위치정보를 지우고 썸네일도 같이 생성
 func InsertFileHandler(c *gin.Context) {
 	uuid, err1 := model.CookieExist(c)
 	if err1 != nil {
 		fmt.Println("ERROR #130 : ", err1.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
 	}
 
+	conn_id, err2 := model.SelectConnIDByUUID(uuid)
+	if err2 != nil {
+		fmt.Println("ERROR #146 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// 종류별 제한 중 가장 큰 값보다 큰 요청은 끝까지 읽지 않고 거절
+	maxLimit := maxUploadSizeLimit()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxLimit+1<<20)
+
 	f, err4 := c.FormFile("file")
 	if err4 != nil {
 		fmt.Println("ERROR #132 : ", err4.Error())
+		if errors.As(err4, new(*http.MaxBytesError)) {
+			c.String(http.StatusRequestEntityTooLarge, "%v", "FILE_TOO_LARGE")
+			return
+		}
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if f.Size > maxLimit {
+		c.String(http.StatusRequestEntityTooLarge, "%v", "FILE_TOO_LARGE")
+		return
 	}
 
-	mimeType := f.Header.Get("Content-Type")
+	file, err6 := f.Open()
+	if err6 != nil {
+		fmt.Println("ERROR #141 : ", err6.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
 
-	if  !strings.Contains(mimeType,"application/") && !strings.Contains(mimeType,"text/") && !strings.Contains(mimeType,"audio/") && !strings.Contains(mimeType,"video/") && !strings.Contains(mimeType,"image/"){
-		c.Writer.WriteHeader(http.StatusBadRequest)
+	// 종류 판별은 앞부분 512byte만 읽어서 하고, 파일 전체는 메모리에 올리지 않음
+	head := make([]byte, 512)
+	n, err7 := io.ReadFull(file, head)
+	if err7 != nil && err7 != io.EOF && err7 != io.ErrUnexpectedEOF {
+		fmt.Println("ERROR #142 : ", err7.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	var err3 error
-	var chatID int
-	if strings.Contains(mimeType,"image/") {
-		chatID, err3 = model.InsertChatAndGetChatID(f.Filename, uuid, getTimeNow().Format("2006-01-02 03:04:05"), 1, 1)
-	} else {
-		chatID, err3 = model.InsertChatAndGetChatID(f.Filename, uuid, getTimeNow().Format("2006-01-02 03:04:05"), 1, 0)
+	mimeType, status, message, err10 := checkUploadAllowed(conn_id, head[:n], f.Size)
+	if err10 != nil {
+		fmt.Println("ERROR #147 : ", err10.Error())
 	}
-	if err3 != nil {
-		fmt.Println("ERROR #132 : ", err3.Error())
+	if status != http.StatusOK {
+		c.String(status, "%v", message)
+		return
 	}
 
-	err5 := c.SaveUploadedFile(f, "assets/"+strconv.Itoa(chatID)+"-"+f.Filename)
+	_, err8 := file.Seek(0, io.SeekStart)
+	if err8 != nil {
+		fmt.Println("ERROR #142 : ", err8.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	isImage := mimeCategory(mimeType) == "image"
+	isVoice := c.PostForm("voice") == "1"
+	if isVoice && mimeCategory(mimeType) != "audio" {
+		c.String(http.StatusUnsupportedMediaType, "%v", "UNSUPPORTED_AUDIO")
+		return
+	}
+
+	// 이미지(위치정보 제거, 썸네일)와 음성 메시지(재생 길이, 파형)만 종류별 용량 제한 안에서 전체를 읽음
+	var content io.Reader = file
+	var data []byte
+	size := f.Size
+	if isImage || isVoice {
+		var err11 error
+		data, err11 = io.ReadAll(file)
+		if err11 != nil {
+			fmt.Println("ERROR #142 : ", err11.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if isImage {
+			data = stripLocationMetadata(data)
+		}
+		content = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+
+	// voice=1로 보낸 녹음 파일은 재생 길이와 파형을 계산해서 음성 메시지로 저장
+	var durationMs int
+	var waveform []int
+	if isVoice {
+		var err12 error
+		durationMs, waveform, err12 = analyzeAudio(data)
+		if err12 != nil {
+			fmt.Println("ERROR #208 : ", err12.Error())
+			c.String(http.StatusUnsupportedMediaType, "%v", "UNSUPPORTED_AUDIO")
+			return
+		}
+	}
+
+	tmpPath, err5 := writeTempUpload(content)
 	if err5 != nil {
 		fmt.Println("ERROR #133 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chatID, filePath, isSaved, err3 := insertFileChat(uuid, conn_id, sanitizeFileName(f.Filename), mimeType, size)
+	if err3 != nil {
+		fmt.Println("ERROR #132 : ", err3.Error())
+		os.Remove(tmpPath)
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isSaved {
+		os.Remove(tmpPath)
+		c.String(http.StatusRequestEntityTooLarge, "%v", "QUOTA_EXCEEDED")
+		return
+	}
+
+	err14 := moveUploadedFile(tmpPath, filePath, chatID)
+	if err14 != nil {
+		fmt.Println("ERROR #133 : ", err14.Error())
+		os.Remove(tmpPath)
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if isVoice {
+		err13 := model.UpdateVoiceByChatID(chatID, durationMs, waveform)
+		if err13 != nil {
+			fmt.Println("ERROR #209 : ", err13.Error())
+		}
+	}
+
+	// 썸네일 생성에 실패해도 원본은 저장되어 있으므로 GetFileHandler에서 원본으로 대체됨
+	if isImage {
+		err9 := generateThumbnails(chatID, data)
+		if err9 != nil {
+			fmt.Println("ERROR #144 : ", err9.Error())
+		}
 	}
 }
 
-func GetFileHandler(c *gin.Context) {
-	chatID := c.Param("chatID")
+// 커플이 사용중인 파일 저장공간 불러오기
+func GetStorageUsageHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #149 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	usedBytes, fileCount, err2 := model.GetStorageUsageByConnID(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #150 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData := struct {
+		UsedBytes int64 `json:"used_bytes"`
+		QuotaBytes int64 `json:"quota_bytes"`
+		FileCount int `json:"file_count"`
+	}{
+		UsedBytes: usedBytes,
+		QuotaBytes: storageQuota(),
+		FileCount: fileCount,
+	}
+
+	marshaledData, err3 := json.Marshal(sendData)
+	if err3 != nil {
+		fmt.Println("ERROR #151 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.Writer.Write(marshaledData)
+}
 
-	var file *os.File
+// assets에서 chatID에 해당하는 원본 파일 경로 찾기, 썸네일 디렉토리는 제외
+func findAssetPathByChatID(chatID string) (string, error) {
+	var filePath string
 	err := filepath.Walk("assets", func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if info.IsDir() {
+			if path == thumbnailDir || path == uploadDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), chatID+"-") {
+			filePath = path
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return filePath, err
+}
+
+// chatID에 해당하는 원본 파일과 썸네일 전부 삭제
+func removeAssetsByChatID(chatID int) error {
+	return filepath.Walk("assets", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path == uploadDir {
+			return filepath.SkipDir
+		}
 		if !info.IsDir() {
-			if strings.Contains(info.Name(), chatID+"-") {
-				file, err = os.Open(path)
+			if strings.HasPrefix(info.Name(), strconv.Itoa(chatID)+"-") {
+				err := os.Remove(path)
 				if err != nil {
 					return err
 				}
@@ -1244,8 +1605,51 @@ func GetFileHandler(c *gin.Context) {
 		}
 		return nil
 	})
+}
+
+// chatpage 렌더링용 파일 불러오기, ?size=로 썸네일 가로 길이를 지정하면 썸네일을 리턴
+func GetFileHandler(c *gin.Context) {
+	chatID := c.Param("chatID")
+
+	filePath := ""
+	if size := c.Query("size"); size != "" {
+		width, err1 := strconv.Atoi(size)
+		if err1 != nil || !isThumbnailWidth(width) {
+			c.String(http.StatusBadRequest, "%v", "INVALID_SIZE")
+			return
+		}
+		chatIDNum, err2 := strconv.Atoi(chatID)
+		if err2 != nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, format := range []string{"jpeg", "png"} {
+			path := thumbnailPath(chatIDNum, width, format)
+			if _, err := os.Stat(path); err == nil {
+				filePath = path
+				break
+			}
+		}
+	}
+
+	// 썸네일이 없는 파일(이미지가 아니거나 이전에 업로드된 파일)은 원본 리턴
+	if filePath == "" {
+		path, err := findAssetPathByChatID(chatID)
+		if err != nil {
+			fmt.Println("ERROR #137 : ", err.Error())
+		}
+		filePath = path
+	}
+	if filePath == "" {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(filePath)
 	if err != nil {
-		fmt.Println("ERROR #137 : ", err.Error())
+		fmt.Println("ERROR #145 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 	defer file.Close()
 
@@ -1260,28 +1664,28 @@ func GetFileHandler(c *gin.Context) {
 func GetFileNameHandler(c *gin.Context) {
 	chatID := c.Param("chatID")
 
-	err := filepath.Walk("assets", func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() {
-			if strings.Contains(info.Name(), chatID+"-") {
-				fileName := strings.TrimPrefix(info.Name(),chatID+"-")
-				sendData := struct {
-					FileName string `json:"filename"`
-				}{
-					FileName: fileName,
-				}
-				marshaledData, err := json.Marshal(sendData)
-				if err != nil {
-					return err
-				}
-
-				c.Writer.Write(marshaledData)
-				return nil
-			}
-		}
-		return err
-	})
+	filePath, err := findAssetPathByChatID(chatID)
 	if err != nil {
 		fmt.Println("ERROR #137 : ", err.Error())
 		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if filePath == "" {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
 	}
-}
\ No newline at end of file
+
+	sendData := struct {
+		FileName string `json:"filename"`
+	}{
+		FileName: strings.TrimPrefix(filepath.Base(filePath), chatID+"-"),
+	}
+	marshaledData, err2 := json.Marshal(sendData)
+	if err2 != nil {
+		fmt.Println("ERROR #137 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.Writer.Write(marshaledData)
+}
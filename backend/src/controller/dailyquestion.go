@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDailyQuestionTime = "21:00"
+	defaultDailyQuestionTimezone = "Asia/Seoul"
+)
+
+var sendTimeRegexp = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// 접속 중인 커넥션만 리턴, 접속하지 않은 사람에게는 다음 접속 때 pending 질문으로 전달됨
+func getOnlineConns(uuids ...string) []*wsConn {
+	target_conn := []*wsConn{}
+
+	mutex.Lock()
+	for _, uuid := range uuids {
+		if conns[uuid] != nil {
+			target_conn = append(target_conn, conns[uuid])
+		}
+	}
+	mutex.Unlock()
+	return target_conn
+}
+
+// 오늘의 질문 설정 불러오기, 설정한 적 없으면 기본값(꺼짐)
+func GetDailyQuestionHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	dailyQuestionData, isExist, err2 := model.GetDailyQuestionByConnID(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #237 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		dailyQuestionData.Send_time = defaultDailyQuestionTime
+		dailyQuestionData.Timezone = defaultDailyQuestionTimezone
+	}
+
+	marshaledData, err3 := json.Marshal(dailyQuestionData)
+	if err3 != nil {
+		fmt.Println("ERROR #238 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 오늘의 질문 켜기/끄기, 보낼 시간(HH:MM), 시간대, 카테고리 설정
+func UpdateDailyQuestionHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var dailyQuestionData model.DailyQuestionData
+	err2 := c.ShouldBindJSON(&dailyQuestionData)
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	dailyQuestionData.Connection_id = conn_id
+	dailyQuestionData.Category = strings.ToLower(strings.TrimSpace(dailyQuestionData.Category))
+
+	if dailyQuestionData.Send_time == "" {
+		dailyQuestionData.Send_time = defaultDailyQuestionTime
+	}
+	if dailyQuestionData.Timezone == "" {
+		dailyQuestionData.Timezone = defaultDailyQuestionTimezone
+	}
+	if !sendTimeRegexp.MatchString(dailyQuestionData.Send_time) {
+		c.String(http.StatusBadRequest, "%v", "INVALID_TIME")
+		return
+	}
+	if _, err := time.LoadLocation(dailyQuestionData.Timezone); err != nil {
+		c.String(http.StatusBadRequest, "%v", "INVALID_TIMEZONE")
+		return
+	}
+	if dailyQuestionData.Category != "" && !categoryRegexp.MatchString(dailyQuestionData.Category) {
+		c.String(http.StatusBadRequest, "%v", "INVALID_CATEGORY")
+		return
+	}
+	if dailyQuestionData.Is_enabled != 0 && dailyQuestionData.Is_enabled != 1 {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err3 := model.UpsertDailyQuestion(dailyQuestionData)
+	if err3 != nil {
+		fmt.Println("ERROR #239 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 1분마다 오늘의 질문을 보낼 시간이 된 커플을 확인
+func StartDailyQuestionScheduler() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for ; true; <-ticker.C {
+			sendDailyQuestions(time.Now())
+		}
+	}()
+}
+
+func sendDailyQuestions(now time.Time) {
+	dailyQuestionDatas, err := model.GetEnabledDailyQuestions()
+	if err != nil {
+		fmt.Println("ERROR #240 : ", err.Error())
+		return
+	}
+
+	for _, dailyQuestionData := range dailyQuestionDatas {
+		loc, err := time.LoadLocation(dailyQuestionData.Timezone)
+		if err != nil {
+			loc = getTimeNow().Location()
+		}
+		// 커플 시간대 기준으로 오늘 아직 안 보냈고 보낼 시간이 지났으면 보냄
+		localNow := now.In(loc)
+		today := localNow.Format("2006-01-02")
+		if dailyQuestionData.Last_sent_date == today || localNow.Format("15:04") < dailyQuestionData.Send_time {
+			continue
+		}
+		sendDailyQuestion(dailyQuestionData.Connection_id, dailyQuestionData.Category, today)
+	}
+}
+
+// 아직 받지 않은 질문 하나를 answer에 추가하고 접속 중인 사람에게 바로 전송
+// 접속 중이 아니면 answer에 빈 답변으로 남아 다음 접속 때 대기 중인 질문으로 전달됨
+func sendDailyQuestion(conn_id int, category, today string) {
+	m := lockQuestion(conn_id)
+	defer m.Unlock()
+
+	question, err := model.GetNextDailyQuestion(conn_id, category)
+	if err != nil {
+		fmt.Println("ERROR #241 : ", err.Error())
+		return
+	}
+
+	// 남은 질문이 없어도 오늘은 확인했다고 기록해서 매분 다시 조회하지 않음
+	questiondatas := questionChat(question.Question_id, question.Question_contents, question.Answer_options)
+	err2 := model.InsertDailyAnswer(questiondatas[0].Write_time, conn_id, question.Question_id, today)
+	if err2 != nil {
+		fmt.Println("ERROR #242 : ", err2.Error())
+		return
+	}
+	if question.Question_id == 0 {
+		return
+	}
+
+	first_uuid, second_uuid, err3 := model.GetConnectionByConnID(conn_id)
+	if err3 != nil {
+		fmt.Println("ERROR #243 : ", err3.Error())
+		return
+	}
+	for _, item := range getOnlineConns(first_uuid, second_uuid) {
+		err := item.WriteJSON(questiondatas)
+		if err != nil {
+			fmt.Println("ERROR #244 : ", err.Error())
+		}
+	}
+}
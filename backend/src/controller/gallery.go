@@ -0,0 +1,467 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultPageLimit = 30
+const maxPageLimit = 100
+
+var monthRegexp = regexp.MustCompile(`^\d{4}-\d{2}$`)
+
+// ?page=(1부터 시작)&limit= 쿼리로 LIMIT, OFFSET 계산
+func getPageParams(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	return limit, (page - 1) * limit
+}
+
+type photoGroup struct {
+	Month string `json:"month"`
+	Photos []model.PhotoData `json:"photos"`
+}
+
+// 같은 달에 올라온 사진끼리 묶기, photos는 최신순으로 정렬되어 있어야 함
+func groupPhotosByMonth(photos []model.PhotoData) []photoGroup {
+	groups := []photoGroup{}
+	for _, photo := range photos {
+		month := photo.Write_time
+		if len(month) >= 7 {
+			month = month[:7]
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Month != month {
+			groups = append(groups, photoGroup{Month: month})
+		}
+		groups[len(groups)-1].Photos = append(groups[len(groups)-1].Photos, photo)
+	}
+	return groups
+}
+
+// 앨범 이름 유효성 검사, 앞뒤 공백 제거 후 1~50자
+func checkAlbumName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	length := utf8.RuneCountInString(name)
+	return name, length > 0 && length <= 50
+}
+
+// URL의 chatID가 커플이 올린 사진인지 확인, 아니면 응답을 쓰고 false 리턴
+func getOwnPhotoChatID(c *gin.Context, conn_id int) (int, bool) {
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return 0, false
+	}
+
+	isExist, err2 := model.CheckPhotoByConnIDAndChatID(conn_id, chatID)
+	if err2 != nil {
+		fmt.Println("ERROR #177 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return 0, false
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return 0, false
+	}
+	return chatID, true
+}
+
+// URL의 albumID가 커플이 만든 앨범인지 확인, 아니면 응답을 쓰고 false 리턴
+func getOwnAlbumID(c *gin.Context, conn_id int) (int, bool) {
+	albumID, err := strconv.Atoi(c.Param("albumID"))
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return 0, false
+	}
+
+	isExist, err2 := model.CheckAlbumByConnIDAndAlbumID(conn_id, albumID)
+	if err2 != nil {
+		fmt.Println("ERROR #178 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return 0, false
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return 0, false
+	}
+	return albumID, true
+}
+
+func writePhotoGroups(c *gin.Context, photos []model.PhotoData) {
+	if len(photos) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	marshaledData, err := json.Marshal(groupPhotosByMonth(photos))
+	if err != nil {
+		fmt.Println("ERROR #179 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 채팅으로 주고받은 사진 모아보기, 달별로 묶어서 리턴 (?page=&limit=&month=YYYY-MM&favorite=1)
+func GetGalleryHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #180 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	month := c.Query("month")
+	if month != "" && !monthRegexp.MatchString(month) {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	limit, offset := getPageParams(c)
+
+	photos, err2 := model.GetPhotosByConnID(conn_id, month, c.Query("favorite") == "1", limit, offset)
+	if err2 != nil {
+		fmt.Println("ERROR #181 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writePhotoGroups(c, photos)
+}
+
+// 사진이 있는 달과 달별 사진 개수 불러오기
+func GetGalleryMonthsHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #182 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	months, err2 := model.GetPhotoMonthsByConnID(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #183 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(months) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	marshaledData, err3 := json.Marshal(months)
+	if err3 != nil {
+		fmt.Println("ERROR #184 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 사진 즐겨찾기 추가
+func InsertFavoriteHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #185 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chatID, ok := getOwnPhotoChatID(c, conn_id)
+	if !ok {
+		return
+	}
+
+	err2 := model.InsertPhotoFavorite(conn_id, chatID)
+	if err2 != nil {
+		fmt.Println("ERROR #186 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 사진 즐겨찾기 취소
+func DeleteFavoriteHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #187 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chatID, ok := getOwnPhotoChatID(c, conn_id)
+	if !ok {
+		return
+	}
+
+	err2 := model.DeletePhotoFavorite(conn_id, chatID)
+	if err2 != nil {
+		fmt.Println("ERROR #188 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 앨범 만들기
+func InsertAlbumHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #189 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var albumData model.AlbumData
+	err2 := c.ShouldBindJSON(&albumData)
+	if err2 != nil {
+		fmt.Println("ERROR #190 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	albumName, ok := checkAlbumName(albumData.Album_name)
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	albumID, err3 := model.InsertAlbum(conn_id, albumName)
+	if err3 != nil {
+		fmt.Println("ERROR #191 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	marshaledData, err4 := json.Marshal(model.AlbumData{Album_id: albumID, Album_name: albumName})
+	if err4 != nil {
+		fmt.Println("ERROR #192 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 앨범 목록 불러오기, 앨범별 사진 개수와 표지(가장 최근 사진) 포함
+func GetAlbumsHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #193 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	albums, err2 := model.GetAlbumsByConnID(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #194 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(albums) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	marshaledData, err3 := json.Marshal(albums)
+	if err3 != nil {
+		fmt.Println("ERROR #195 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 앨범 이름 변경
+func UpdateAlbumHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #196 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	albumID, ok := getOwnAlbumID(c, conn_id)
+	if !ok {
+		return
+	}
+
+	var albumData model.AlbumData
+	err2 := c.ShouldBindJSON(&albumData)
+	if err2 != nil {
+		fmt.Println("ERROR #197 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	albumName, ok := checkAlbumName(albumData.Album_name)
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err3 := model.UpdateAlbumName(albumID, albumName)
+	if err3 != nil {
+		fmt.Println("ERROR #198 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 앨범 삭제, 앨범에 담긴 사진은 채팅에 그대로 남음
+func DeleteAlbumHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #199 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	albumID, ok := getOwnAlbumID(c, conn_id)
+	if !ok {
+		return
+	}
+
+	err2 := model.DeleteAlbumByAlbumID(albumID)
+	if err2 != nil {
+		fmt.Println("ERROR #200 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 앨범에 담긴 사진 불러오기 (?page=&limit=)
+func GetAlbumPhotosHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #201 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	albumID, ok := getOwnAlbumID(c, conn_id)
+	if !ok {
+		return
+	}
+	limit, offset := getPageParams(c)
+
+	photos, err2 := model.GetPhotosByAlbumID(albumID, limit, offset)
+	if err2 != nil {
+		fmt.Println("ERROR #202 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writePhotoGroups(c, photos)
+}
+
+// 앨범에 사진 담기
+func InsertAlbumPhotoHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #203 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	albumID, ok := getOwnAlbumID(c, conn_id)
+	if !ok {
+		return
+	}
+	chatID, ok := getOwnPhotoChatID(c, conn_id)
+	if !ok {
+		return
+	}
+
+	err2 := model.InsertAlbumPhoto(albumID, chatID)
+	if err2 != nil {
+		fmt.Println("ERROR #204 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 앨범에서 사진 빼기
+func DeleteAlbumPhotoHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		fmt.Println("ERROR #205 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	albumID, ok := getOwnAlbumID(c, conn_id)
+	if !ok {
+		return
+	}
+	chatID, err2 := strconv.Atoi(c.Param("chatID"))
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err3 := model.DeleteAlbumPhoto(albumID, chatID)
+	if err3 != nil {
+		fmt.Println("ERROR #206 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 갤러리가 생기기 전에 보낸 사진도 보이도록, 저장된 파일로 첨부파일 레코드를 채워넣음
+// 이미 채워진 사진은 다시 조회되지 않으므로 서버를 시작할 때마다 실행해도 됨
+func BackfillPhotoAttachments() {
+	photos, err := model.GetImageChatsWithoutAttachment()
+	if err != nil {
+		fmt.Println("ERROR #318 : ", err.Error())
+		return
+	}
+
+	for _, photo := range photos {
+		f, err := os.Open("assets/" + strconv.Itoa(photo.Chat_id) + "-" + photo.File_name)
+		if err != nil {
+			fmt.Println("ERROR #319 : ", err.Error())
+			continue
+		}
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		info, err2 := f.Stat()
+		f.Close()
+		if err2 != nil {
+			fmt.Println("ERROR #319 : ", err2.Error())
+			continue
+		}
+
+		photo.Mime_type = detectMimeType(head[:n])
+		photo.File_size = info.Size()
+		err3 := model.InsertAttachment(photo)
+		if err3 != nil {
+			fmt.Println("ERROR #320 : ", err3.Error())
+		}
+	}
+}
@@ -0,0 +1,844 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	icalProdID = "-//couple-chat-service//Anniversary Calendar//KO"
+	// 내보낸 일정의 UID 뒤에 붙는 값, 가져올 때 이 서비스가 만든 일정인지 확인할 때도 사용
+	icalUIDDomain = "couple-chat-service"
+	icalCalendarName = "우리 기념일"
+	icalDateLayout = "20060102"
+	icalDateTimeLayout = "20060102T150405"
+	// 음력 반복 일정은 RRULE로 표현할 수 없어서 올해 전후로 펼쳐서 내보내는 연수
+	icalLunarYears = 10
+	// VTIMEZONE에 서머타임 변경을 적는 과거 연수, 아주 오래된 일정이 있어도 매 요청마다 수백년을 계산하지 않도록 제한
+	icalTimezoneHistoryYears = 5
+	// 구독하는 캘린더 앱이 다시 불러오는 간격
+	icalRefreshInterval = "PT6H"
+	calendarFeedTokenBytes = 24
+	maxICalImportEvents = 500
+	maxICalImportSize = 1 << 20
+)
+
+var icalDurationRegexp = regexp.MustCompile(`^\+?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// RFC 5545 TEXT 값 이스케이프
+func icalEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+func icalUnescape(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(value)
+}
+
+// 75바이트가 넘는 줄은 다음 줄을 공백으로 시작해서 접음, UTF-8 글자 중간에서는 자르지 않음
+func foldICalLine(line string) string {
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		b.WriteString(line[:i])
+		b.WriteString("\r\n ")
+		line = line[i:]
+		// 이어지는 줄은 앞의 공백까지 75바이트
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+type icalWriter struct {
+	b strings.Builder
+}
+
+func (w *icalWriter) line(name, value string) {
+	w.b.WriteString(foldICalLine(name + ":" + value))
+}
+
+// UTC offset을 +0900 형식으로
+func icalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// fromYear ~ toYear 사이에 UTC offset이 바뀌는 시각들 (서머타임 시작/끝)
+func zoneTransitions(loc *time.Location, fromYear, toYear int) []time.Time {
+	offsetAt := func(t time.Time) int {
+		_, offset := t.In(loc).Zone()
+		return offset
+	}
+	transitions := []time.Time{}
+	end := time.Date(toYear+1, 1, 1, 0, 0, 0, 0, loc)
+	for day := time.Date(fromYear, 1, 1, 0, 0, 0, 0, loc); day.Before(end); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if offsetAt(day) == offsetAt(next) {
+			continue
+		}
+		// 하루 안에서 바뀌는 시각을 초 단위로 찾음
+		lo, hi := day.Unix(), next.Unix()
+		for hi-lo > 1 {
+			mid := (lo + hi) / 2
+			if offsetAt(time.Unix(mid, 0)) == offsetAt(day) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transitions = append(transitions, time.Unix(hi, 0).In(loc))
+	}
+	return transitions
+}
+
+// TZID로 쓰는 시간대의 VTIMEZONE, fromYear ~ toYear 사이의 서머타임 변경을 하나씩 적음
+func (w *icalWriter) timezone(loc *time.Location, fromYear, toYear int) {
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", loc.String())
+
+	first := time.Date(fromYear, 1, 1, 0, 0, 0, 0, loc)
+	name, offset := first.Zone()
+	observance := func(t time.Time, from, to int, name string, isDST bool) {
+		kind := "STANDARD"
+		if isDST {
+			kind = "DAYLIGHT"
+		}
+		w.line("BEGIN", kind)
+		w.line("DTSTART", t.In(time.FixedZone("", from)).Format(icalDateTimeLayout))
+		w.line("TZOFFSETFROM", icalOffset(from))
+		w.line("TZOFFSETTO", icalOffset(to))
+		w.line("TZNAME", name)
+		w.line("END", kind)
+	}
+	observance(time.Date(1970, 1, 1, 0, 0, 0, 0, time.FixedZone("", offset)), offset, offset, name, first.IsDST())
+	for _, t := range zoneTransitions(loc, fromYear, toYear) {
+		newName, newOffset := t.Zone()
+		observance(t, offset, newOffset, newName, t.IsDST())
+		offset = newOffset
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+// 일정의 반복 규칙을 RRULE로, 반복하지 않으면 ""
+// 달에 없는 날짜는 그 달 마지막 날로 맞추는 것과 같도록 29~31일은 BYSETPOS=-1로 마지막 날을 고름
+func icalRecurrenceRule(anniversary model.AnniversaryData) string {
+	lastDays := func(date int) string {
+		days := []string{}
+		for day := 28; day <= date; day++ {
+			days = append(days, strconv.Itoa(day))
+		}
+		return "BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+	}
+	switch anniversary.Recurrence {
+	case "yearly":
+		if anniversary.Month == 2 && anniversary.Date == 29 {
+			return "FREQ=YEARLY;BYMONTH=2;" + lastDays(29)
+		}
+		return "FREQ=YEARLY"
+	case "monthly":
+		if anniversary.Date > 28 {
+			return "FREQ=MONTHLY;" + lastDays(anniversary.Date)
+		}
+		return "FREQ=MONTHLY"
+	case "days":
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", anniversary.Interval_days)
+	}
+	return ""
+}
+
+// VEVENT 하나, 하루 종일 일정은 날짜만, 시간 일정은 일정 시간대(TZID) 기준으로 적음
+func (w *icalWriter) event(uid string, anniversary model.AnniversaryData, start, end time.Time, loc *time.Location, rrule, category string, stamp time.Time) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", uid)
+	w.line("DTSTAMP", stamp.UTC().Format(icalDateTimeLayout)+"Z")
+	switch {
+	case anniversary.Is_all_day:
+		w.line("DTSTART;VALUE=DATE", start.In(loc).Format(icalDateLayout))
+		w.line("DTEND;VALUE=DATE", end.In(loc).Format(icalDateLayout))
+	case loc == time.UTC:
+		w.line("DTSTART", start.UTC().Format(icalDateTimeLayout)+"Z")
+		w.line("DTEND", end.UTC().Format(icalDateTimeLayout)+"Z")
+	default:
+		w.line("DTSTART;TZID="+loc.String(), start.In(loc).Format(icalDateTimeLayout))
+		w.line("DTEND;TZID="+loc.String(), end.In(loc).Format(icalDateTimeLayout))
+	}
+	if rrule != "" {
+		w.line("RRULE", rrule)
+	}
+	w.line("SUMMARY", icalEscape(anniversary.Contents))
+	if anniversary.Location != "" {
+		w.line("LOCATION", icalEscape(anniversary.Location))
+	}
+	if anniversary.Notes != "" {
+		w.line("DESCRIPTION", icalEscape(anniversary.Notes))
+	}
+	if category != "" {
+		w.line("CATEGORIES", category)
+	}
+	w.line("TRANSP", "TRANSPARENT")
+	w.line("END", "VEVENT")
+}
+
+// 커플 일정 전체를 iCalendar로 만듦
+// 반복 일정은 RRULE, 음력 반복 일정은 올해 전후로 펼친 날짜들, D-DAY 일정과 사귄 날부터 계산한 기념일도 포함
+func buildICalendar(conn_id int, anniversaries []model.AnniversaryData, start_date string, now time.Time) []byte {
+	w := &icalWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", icalProdID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", icalEscape(icalCalendarName))
+	w.line("X-WR-TIMEZONE", now.Location().String())
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", icalRefreshInterval)
+	w.line("X-PUBLISHED-TTL", icalRefreshInterval)
+
+	// TZID로 쓰는 시간대는 VTIMEZONE을 먼저 적어야 해서 일정은 따로 모았다가 씀
+	events := &icalWriter{}
+	timezones := make(map[string]*time.Location)
+	fromYear := now.Year()
+	for _, anniversary := range anniversaries {
+		start, end, ok := eventTimes(anniversary)
+		if !ok {
+			continue
+		}
+		loc := eventLocation(anniversary)
+		category := ""
+		if anniversary.D_day {
+			category = "D-DAY"
+		}
+
+		if anniversary.Is_lunar && anniversary.Recurrence == "yearly" {
+			for year := now.Year() - 1; year <= now.Year()+icalLunarYears; year++ {
+				t, ok := lunarToSolar(year, anniversary.Month, anniversary.Date, anniversary.Is_leap_month)
+				if !ok || t.Before(time.Date(start.In(loc).Year(), start.In(loc).Month(), start.In(loc).Day(), 0, 0, 0, 0, time.UTC)) {
+					continue
+				}
+				occurrenceStart, occurrenceEnd := occurrenceTimes(anniversary, start, end, t, loc)
+				events.event(fmt.Sprintf("anniversary-%d-%d@%s", anniversary.Anniversary_id, year, icalUIDDomain), anniversary, occurrenceStart, occurrenceEnd, loc, "", category, now)
+			}
+			continue
+		}
+
+		if !anniversary.Is_all_day && loc != time.UTC {
+			timezones[loc.String()] = loc
+			if year := start.In(loc).Year(); year < fromYear {
+				fromYear = year
+			}
+			if fromYear < now.Year()-icalTimezoneHistoryYears {
+				fromYear = now.Year() - icalTimezoneHistoryYears
+			}
+		}
+		events.event(fmt.Sprintf("anniversary-%d@%s", anniversary.Anniversary_id, icalUIDDomain), anniversary, start, end, loc, icalRecurrenceRule(anniversary), category, now)
+	}
+
+	// 자동 기념일은 서버 시간대 기준 하루 종일 일정
+	loc := now.Location()
+	for _, milestone := range milestonesInRange(conn_id, start_date, time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, loc), loc) {
+		day, err := time.ParseInLocation("2006-01-02", milestone.Start_time, loc)
+		if err != nil {
+			continue
+		}
+		events.event(fmt.Sprintf("milestone-%d-%s@%s", conn_id, day.Format(icalDateLayout), icalUIDDomain), milestone, day, day.AddDate(0, 0, 1), loc, "", "MILESTONE", now)
+	}
+
+	names := []string{}
+	for name := range timezones {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w.timezone(timezones[name], fromYear, now.Year()+icalLunarYears)
+	}
+	w.b.WriteString(events.b.String())
+	w.line("END", "VCALENDAR")
+	return []byte(w.b.String())
+}
+
+// 커플 iCalendar 파일 내용
+func getCoupleICalendar(conn_id int) ([]byte, error) {
+	anniversaries, err := model.GetAnniversariesByConnID(conn_id)
+	if err != nil {
+		return nil, err
+	}
+	start_date, err := model.GetConnectionStartDate(conn_id)
+	if err != nil {
+		return nil, err
+	}
+	return buildICalendar(conn_id, anniversaries, start_date, getTimeNow()), nil
+}
+
+func writeICalendar(c *gin.Context, data []byte) {
+	c.Writer.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Write(data)
+}
+
+// iCalendar 한 줄 (NAME;PARAM=VALUE:VALUE)
+type icalProperty struct {
+	name string
+	params map[string]string
+	value string
+}
+
+// 접힌 줄을 이어붙인 줄들
+func unfoldICalLines(data []byte) []string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// 따옴표 안의 ;와 :는 구분자로 보지 않음
+func parseICalProperty(line string) (icalProperty, bool) {
+	property := icalProperty{params: make(map[string]string)}
+	isQuoted := false
+	parts := []string{}
+	last := 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			isQuoted = !isQuoted
+		case r == ';' && !isQuoted:
+			parts = append(parts, line[last:i])
+			last = i + 1
+		case r == ':' && !isQuoted:
+			parts = append(parts, line[last:i])
+			property.value = line[i+1:]
+			property.name = strings.ToUpper(parts[0])
+			for _, param := range parts[1:] {
+				if key, value, ok := strings.Cut(param, "="); ok {
+					property.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+				}
+			}
+			return property, property.name != ""
+		}
+	}
+	return property, false
+}
+
+// VEVENT 하나의 속성들, 같은 이름이 여러 번 나오면 처음 것만 사용
+type icalEvent map[string]icalProperty
+
+// iCalendar 파일의 VEVENT들, VEVENT 안의 VALARM 같은 하위 항목은 무시
+// X-WR-TIMEZONE이 있으면 시간대 없는 시간을 그 시간대로 해석하도록 리턴
+func parseICalendar(data []byte) ([]icalEvent, string, *importError) {
+	lines := unfoldICalLines(data)
+	if len(lines) == 0 {
+		return nil, "", &importError{0, "EMPTY_FILE"}
+	}
+	if !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, "", &importError{0, "INVALID_ICS"}
+	}
+
+	events := []icalEvent{}
+	calendarTimezone := ""
+	components := []string{}
+	for _, line := range lines {
+		property, ok := parseICalProperty(line)
+		if !ok {
+			continue
+		}
+		switch property.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(property.value))
+			if len(components) == 2 && components[1] == "VEVENT" {
+				events = append(events, icalEvent{})
+			}
+			continue
+		case "END":
+			if len(components) > 0 {
+				components = components[:len(components)-1]
+			}
+			continue
+		}
+		if len(components) == 1 && property.name == "X-WR-TIMEZONE" {
+			calendarTimezone = property.value
+		}
+		if len(components) == 2 && components[1] == "VEVENT" {
+			event := events[len(events)-1]
+			if _, ok := event[property.name]; !ok {
+				event[property.name] = property
+			}
+		}
+	}
+	return events, calendarTimezone, nil
+}
+
+// DTSTART/DTEND 값을 시간으로, 날짜만 있으면 isDate가 true
+// TZID가 IANA 시간대 이름이 아니면(Windows 시간대 이름 등) defaultLoc 기준으로 해석
+func parseICalTime(property icalProperty, defaultLoc *time.Location) (time.Time, bool, *time.Location, bool) {
+	value := property.value
+	if strings.EqualFold(property.params["VALUE"], "DATE") || len(value) == len(icalDateLayout) {
+		t, err := time.ParseInLocation(icalDateLayout, value, defaultLoc)
+		return t, true, defaultLoc, err == nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.ParseInLocation(icalDateTimeLayout, strings.TrimSuffix(value, "Z"), time.UTC)
+		return t, false, time.UTC, err == nil
+	}
+	loc := defaultLoc
+	if tzid := property.params["TZID"]; tzid != "" {
+		if tzLoc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = tzLoc
+		}
+	}
+	t, err := time.ParseInLocation(icalDateTimeLayout, value, loc)
+	return t, false, loc, err == nil
+}
+
+// DURATION 값, P1D처럼 날짜 단위 부분과 PT1H처럼 시간 단위 부분을 나눠서 리턴
+func parseICalDuration(value string) (int, time.Duration, bool) {
+	matches := icalDurationRegexp.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value == "PT" {
+		return 0, 0, false
+	}
+	n := make([]int, len(matches))
+	for i := 1; i < len(matches); i++ {
+		n[i], _ = strconv.Atoi(matches[i])
+	}
+	return n[1]*7 + n[2], time.Duration(n[3])*time.Hour + time.Duration(n[4])*time.Minute + time.Duration(n[5])*time.Second, true
+}
+
+// RRULE을 일정 반복 방식으로, 표현할 수 없는 규칙(COUNT, UNTIL, 여러 요일 등)이면 false
+// BYMONTH, BYMONTHDAY, BYDAY는 시작 날짜와 같은 날을 가리킬 때만 허용
+func parseICalRecurrence(rrule string, start time.Time) (string, int, bool) {
+	rule := make(map[string]string)
+	for _, part := range strings.Split(strings.ToUpper(rrule), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", 0, false
+		}
+		rule[key] = value
+	}
+
+	interval := 1
+	if rule["INTERVAL"] != "" {
+		n, err := strconv.Atoi(rule["INTERVAL"])
+		if err != nil || n < 1 {
+			return "", 0, false
+		}
+		interval = n
+	}
+	monthDayOK := func() bool {
+		days := strings.Split(rule["BYMONTHDAY"], ",")
+		if rule["BYMONTHDAY"] == "" {
+			return rule["BYSETPOS"] == ""
+		}
+		if days[len(days)-1] != strconv.Itoa(start.Day()) {
+			return false
+		}
+		// 여러 날짜는 내보낼 때처럼 달의 마지막 날로 맞추는 경우만
+		return (len(days) == 1 && rule["BYSETPOS"] == "") || (rule["BYSETPOS"] == "-1" && days[0] == "28")
+	}
+
+	for key, value := range rule {
+		switch key {
+		case "FREQ", "INTERVAL", "WKST":
+		case "BYMONTH":
+			if rule["FREQ"] != "YEARLY" || value != strconv.Itoa(int(start.Month())) {
+				return "", 0, false
+			}
+		case "BYMONTHDAY", "BYSETPOS":
+			if (rule["FREQ"] != "YEARLY" && rule["FREQ"] != "MONTHLY") || !monthDayOK() {
+				return "", 0, false
+			}
+		case "BYDAY":
+			if rule["FREQ"] != "WEEKLY" || value != strings.ToUpper(start.Weekday().String()[:2]) {
+				return "", 0, false
+			}
+		default:
+			return "", 0, false
+		}
+	}
+	// 매년/매달은 간격을 저장할 수 없어서 INTERVAL=1만 가능
+	switch rule["FREQ"] {
+	case "YEARLY":
+		return "yearly", 0, interval == 1
+	case "MONTHLY":
+		return "monthly", 0, interval == 1
+	case "WEEKLY":
+		return "days", interval * 7, true
+	case "DAILY":
+		return "days", interval, true
+	}
+	return "", 0, false
+}
+
+// VEVENT를 일정 추가 요청 형식으로 바꿈, 잘못된 값이면 에러 메시지 리턴
+func icalEventToAnniversary(event icalEvent, defaultLoc *time.Location) (model.AnniversaryData, string) {
+	var anniversary model.AnniversaryData
+	dtstart, ok := event["DTSTART"]
+	if !ok {
+		return anniversary, "MISSING_DTSTART"
+	}
+	start, isDate, loc, ok := parseICalTime(dtstart, defaultLoc)
+	if !ok {
+		return anniversary, "INVALID_DTSTART"
+	}
+	anniversary.Contents = icalUnescape(event["SUMMARY"].value)
+	anniversary.Location = icalUnescape(event["LOCATION"].value)
+	anniversary.Notes = icalUnescape(event["DESCRIPTION"].value)
+	anniversary.Timezone = loc.String()
+	anniversary.Is_all_day = isDate
+	if isDate {
+		anniversary.Start_time = start.Format("2006-01-02")
+	} else {
+		anniversary.Start_time = start.Format(time.RFC3339)
+	}
+
+	// 하루 종일 일정의 DTEND는 끝나는 다음 날, 일정 추가 요청은 마지막 날
+	var end time.Time
+	if dtend, ok := event["DTEND"]; ok {
+		t, endIsDate, _, ok := parseICalTime(dtend, loc)
+		if !ok || endIsDate != isDate {
+			return anniversary, "INVALID_DTEND"
+		}
+		end = t
+	} else if duration, ok := event["DURATION"]; ok {
+		days, d, ok := parseICalDuration(duration.value)
+		if !ok || (isDate && d != 0) {
+			return anniversary, "INVALID_DURATION"
+		}
+		end = start.AddDate(0, 0, days).Add(d)
+	}
+	switch {
+	case end.IsZero():
+	case isDate && end.After(start):
+		anniversary.End_time = end.AddDate(0, 0, -1).Format("2006-01-02")
+	case isDate:
+		// DTEND가 DTSTART와 같은 하루 종일 일정은 그 날 하루
+	default:
+		anniversary.End_time = end.Format(time.RFC3339)
+	}
+
+	if rrule, ok := event["RRULE"]; ok {
+		recurrence, interval, ok := parseICalRecurrence(rrule.value, start.In(loc))
+		if !ok {
+			return anniversary, "UNSUPPORTED_RECURRENCE"
+		}
+		anniversary.Recurrence, anniversary.Interval_days = recurrence, interval
+	}
+	if _, ok := event["RDATE"]; ok {
+		return anniversary, "UNSUPPORTED_RECURRENCE"
+	}
+	if _, ok := event["EXDATE"]; ok {
+		return anniversary, "UNSUPPORTED_RECURRENCE"
+	}
+
+	if message := normalizeEvent(&anniversary); message != "" {
+		return anniversary, message
+	}
+	return anniversary, validateAnniversary(anniversary)
+}
+
+// 같은 일정인지 비교할 때 쓰는 값
+func anniversaryKey(anniversary model.AnniversaryData) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d", anniversary.Contents, anniversary.Start_time, anniversary.End_time, anniversary.Recurrence, anniversary.Interval_days)
+}
+
+func newCalendarFeedToken() (string, error) {
+	b := make([]byte, calendarFeedTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type calendarFeed struct {
+	Token string `json:"token"`
+	Url string `json:"url"`
+	Webcal_url string `json:"webcal_url"`
+}
+
+// 요청 받은 주소 기준 구독 주소, 캘린더 앱에서 바로 구독할 수 있는 webcal:// 주소도 같이 보냄
+func getCalendarFeed(c *gin.Context, token string) calendarFeed {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	path := c.Request.Host + "/api/calendar/feed/" + token + ".ics"
+	return calendarFeed{token, scheme + "://" + path, "webcal://" + path}
+}
+
+func writeCalendarFeed(c *gin.Context, token string) {
+	marshaledData, err := json.Marshal(getCalendarFeed(c, token))
+	if err != nil {
+		fmt.Println("ERROR #294 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 커플 일정을 .ics 파일로 내보내기
+func ExportCalendarHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	data, err2 := getCoupleICalendar(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #295 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Header().Set("Content-Disposition", `attachment; filename="anniversary.ics"`)
+	writeICalendar(c, data)
+}
+
+// 구독 주소 불러오기, 만든 적 없으면 204
+func GetCalendarFeedHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	token, err2 := model.GetCalendarFeedToken(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #296 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if token == "" {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeCalendarFeed(c, token)
+}
+
+// 구독 주소 만들기, 이미 있으면 새 주소로 바꿔서 예전 주소로는 더 이상 구독할 수 없음
+func CreateCalendarFeedHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	token, err2 := newCalendarFeedToken()
+	if err2 != nil {
+		fmt.Println("ERROR #297 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	err3 := model.UpsertCalendarFeedToken(conn_id, token)
+	if err3 != nil {
+		fmt.Println("ERROR #298 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeCalendarFeed(c, token)
+}
+
+// 구독 주소 삭제
+func DeleteCalendarFeedHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err2 := model.DeleteCalendarFeedToken(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #299 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 캘린더 앱이 구독하는 주소, 로그인 없이 토큰으로 커플을 찾음
+func CalendarFeedHandler(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	conn_id, err := model.GetConnIDByCalendarFeedToken(token)
+	if err != nil {
+		fmt.Println("ERROR #300 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if conn_id == 0 {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	data, err2 := getCoupleICalendar(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #301 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeICalendar(c, data)
+}
+
+// .ics 파일의 일정들을 한 번에 추가 (?tz=, 시간대 없는 시간을 해석할 기준)
+// multipart의 file 필드나 text/calendar body로 받음, 하나라도 잘못되면 아무것도 저장하지 않고 일정 순서와 에러 리턴
+// 이미 있는 일정과 사귄 날부터 자동으로 만든 기념일, 취소된 일정은 건너뜀
+func ImportCalendarHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	defaultLoc, ok := getTimezoneParam(c)
+	if !ok {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxICalImportSize+(1<<20))
+	var data []byte
+	if c.ContentType() == "multipart/form-data" {
+		file, err2 := c.FormFile("file")
+		if err2 != nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if file.Size > maxICalImportSize {
+			c.Writer.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		f, err3 := file.Open()
+		if err3 != nil {
+			fmt.Println("ERROR #302 : ", err3.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		data, err = io.ReadAll(f)
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxICalImportSize {
+		c.Writer.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	importErrors := []importError{}
+	events, calendarTimezone, icsError := parseICalendar(data)
+	if icsError != nil {
+		importErrors = append(importErrors, *icsError)
+	} else if len(events) == 0 {
+		importErrors = append(importErrors, importError{0, "EMPTY_FILE"})
+	} else if len(events) > maxICalImportEvents {
+		importErrors = append(importErrors, importError{0, "TOO_MANY_EVENTS"})
+	}
+	if loc, err := time.LoadLocation(calendarTimezone); calendarTimezone != "" && err == nil && c.Query("tz") == "" {
+		defaultLoc = loc
+	}
+
+	stored, err4 := model.GetAnniversariesByConnID(conn_id)
+	if err4 != nil {
+		fmt.Println("ERROR #303 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	keys := make(map[string]bool)
+	for _, anniversary := range stored {
+		keys[anniversaryKey(anniversary)] = true
+	}
+
+	anniversaries := []model.AnniversaryData{}
+	skipped := 0
+	for i, event := range events {
+		if len(importErrors) > 0 && importErrors[0].Row == 0 {
+			// 파일 전체가 잘못된 경우
+			break
+		}
+		uid := event["UID"].value
+		if strings.EqualFold(event["STATUS"].value, "CANCELLED") || (strings.HasPrefix(uid, "milestone-") && strings.HasSuffix(uid, "@"+icalUIDDomain)) {
+			skipped++
+			continue
+		}
+		anniversary, message := icalEventToAnniversary(event, defaultLoc)
+		if message != "" {
+			importErrors = append(importErrors, importError{i + 1, message})
+			continue
+		}
+		if keys[anniversaryKey(anniversary)] {
+			skipped++
+			continue
+		}
+		keys[anniversaryKey(anniversary)] = true
+		anniversary.Connection_id = conn_id
+		anniversaries = append(anniversaries, anniversary)
+	}
+
+	if len(importErrors) > 0 {
+		marshaledData, err := json.Marshal(struct {
+			Errors []importError `json:"errors"`
+		}{importErrors})
+		if err != nil {
+			fmt.Println("ERROR #304 : ", err.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		c.Writer.Write(marshaledData)
+		return
+	}
+
+	err5 := model.InsertAnniversaries(anniversaries)
+	if err5 != nil {
+		fmt.Println("ERROR #305 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData := struct {
+		Added int `json:"added"`
+		Skipped int `json:"skipped"`
+	}{len(anniversaries), skipped}
+
+	marshaledData, err6 := json.Marshal(sendData)
+	if err6 != nil {
+		fmt.Println("ERROR #306 : ", err6.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
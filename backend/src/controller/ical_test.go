@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+)
+
+func TestFoldICalLine(t *testing.T) {
+	tests := []string{
+		"SUMMARY:짧은 일정",
+		"DESCRIPTION:" + strings.Repeat("a", 63),
+		"DESCRIPTION:" + strings.Repeat("a", 64),
+		"DESCRIPTION:" + strings.Repeat("a", 300),
+		"LOCATION:" + strings.Repeat("서울특별시 ", 40),
+		"SUMMARY:" + strings.Repeat("🎉", 50),
+	}
+
+	for _, line := range tests {
+		folded := foldICalLine(line)
+		if !strings.HasSuffix(folded, "\r\n") {
+			t.Errorf("foldICalLine(%q) does not end with CRLF", line)
+		}
+		for _, physical := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+			if len(physical) > 75 {
+				t.Errorf("foldICalLine(%q) has a %d byte line", line, len(physical))
+			}
+			if !utf8.ValidString(physical) {
+				t.Errorf("foldICalLine(%q) split a UTF-8 character", line)
+			}
+		}
+		if got := unfoldICalLines([]byte(folded)); len(got) != 1 || got[0] != line {
+			t.Errorf("unfoldICalLines(foldICalLine(%q)) = %q", line, got)
+		}
+	}
+}
+
+func TestICalEscape(t *testing.T) {
+	tests := []string{
+		"",
+		"기념일",
+		`a\b;c,d`,
+		"첫째 줄\n둘째 줄",
+	}
+
+	for _, value := range tests {
+		if got := icalUnescape(icalEscape(value)); got != value {
+			t.Errorf("icalUnescape(icalEscape(%q)) = %q", value, got)
+		}
+	}
+}
+
+func TestICalRecurrenceRoundTrip(t *testing.T) {
+	tests := []struct {
+		anniversary model.AnniversaryData
+		rrule string
+	}{
+		{model.AnniversaryData{Year: 2024, Month: 5, Date: 1, Recurrence: "yearly"}, "FREQ=YEARLY"},
+		{model.AnniversaryData{Year: 2024, Month: 2, Date: 29, Recurrence: "yearly"}, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=28,29;BYSETPOS=-1"},
+		{model.AnniversaryData{Year: 2024, Month: 1, Date: 15, Recurrence: "monthly"}, "FREQ=MONTHLY"},
+		{model.AnniversaryData{Year: 2024, Month: 1, Date: 31, Recurrence: "monthly"}, "FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+		{model.AnniversaryData{Year: 2024, Month: 1, Date: 1, Recurrence: "days", Interval_days: 1}, "FREQ=DAILY;INTERVAL=1"},
+		{model.AnniversaryData{Year: 2024, Month: 1, Date: 1, Recurrence: "days", Interval_days: 100}, "FREQ=DAILY;INTERVAL=100"},
+		{model.AnniversaryData{Year: 2024, Month: 1, Date: 1}, ""},
+	}
+
+	for _, tt := range tests {
+		rrule := icalRecurrenceRule(tt.anniversary)
+		if rrule != tt.rrule {
+			t.Errorf("icalRecurrenceRule(%+v) = %q, want %q", tt.anniversary, rrule, tt.rrule)
+			continue
+		}
+		if rrule == "" {
+			continue
+		}
+		start := time.Date(tt.anniversary.Year, time.Month(tt.anniversary.Month), tt.anniversary.Date, 0, 0, 0, 0, time.UTC)
+		recurrence, interval, ok := parseICalRecurrence(rrule, start)
+		if !ok || recurrence != tt.anniversary.Recurrence || interval != tt.anniversary.Interval_days {
+			t.Errorf("parseICalRecurrence(%q) = %q, %d, %v, want %q, %d", rrule, recurrence, interval, ok, tt.anniversary.Recurrence, tt.anniversary.Interval_days)
+		}
+	}
+}
+
+func TestParseICalRecurrence(t *testing.T) {
+	// 2024-01-01은 월요일
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rrule string
+		recurrence string
+		interval int
+		ok bool
+	}{
+		{"FREQ=WEEKLY", "days", 7, true},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "days", 14, true},
+		{"freq=daily;interval=3", "days", 3, true},
+		{"FREQ=MONTHLY;BYMONTHDAY=1", "monthly", 0, true},
+		{"FREQ=YEARLY;BYMONTH=1;WKST=MO", "yearly", 0, true},
+		// 저장할 수 없는 규칙
+		{"FREQ=WEEKLY;BYDAY=TU", "", 0, false},
+		{"FREQ=YEARLY;INTERVAL=2", "", 0, false},
+		{"FREQ=MONTHLY;BYDAY=1MO", "", 0, false},
+		{"FREQ=MONTHLY;BYMONTHDAY=15", "", 0, false},
+		{"FREQ=DAILY;COUNT=10", "", 0, false},
+		{"FREQ=HOURLY", "", 0, false},
+		{"FREQ=DAILY;INTERVAL=0", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		recurrence, interval, ok := parseICalRecurrence(tt.rrule, start)
+		if ok != tt.ok || (ok && (recurrence != tt.recurrence || interval != tt.interval)) {
+			t.Errorf("parseICalRecurrence(%q) = %q, %d, %v, want %q, %d, %v", tt.rrule, recurrence, interval, ok, tt.recurrence, tt.interval, tt.ok)
+		}
+	}
+}
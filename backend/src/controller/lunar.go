@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"time"
+)
+
+// 음력 변환이 가능한 연도 범위
+const (
+	minLunarYear = 1900
+	maxLunarYear = 2100
+)
+
+// 1900~2100년 음력 정보, 연도마다 하나
+// 중국 음력(UTC+8) 기준 표라서 한국천문연구원 음력과 초하루가 하루 다른 달이 드물게 있음
+// 0~3비트: 윤달(0이면 없음), 4~15비트: 1~12월이 30일이면 1(1월이 가장 높은 비트), 16비트: 윤달이 30일이면 1
+var lunarInfo = [...]int{
+	0x04bd8, 0x04ae0, 0x0a570, 0x054d5, 0x0d260, 0x0d950, 0x16554, 0x056a0, 0x09ad0, 0x055d2,
+	0x04ae0, 0x0a5b6, 0x0a4d0, 0x0d250, 0x1d255, 0x0b540, 0x0d6a0, 0x0ada2, 0x095b0, 0x14977,
+	0x04970, 0x0a4b0, 0x0b4b5, 0x06a50, 0x06d40, 0x1ab54, 0x02b60, 0x09570, 0x052f2, 0x04970,
+	0x06566, 0x0d4a0, 0x0ea50, 0x16a95, 0x05ad0, 0x02b60, 0x186e3, 0x092e0, 0x1c8d7, 0x0c950,
+	0x0d4a0, 0x1d8a6, 0x0b550, 0x056a0, 0x1a5b4, 0x025d0, 0x092d0, 0x0d2b2, 0x0a950, 0x0b557,
+	0x06ca0, 0x0b550, 0x15355, 0x04da0, 0x0a5b0, 0x14573, 0x052b0, 0x0a9a8, 0x0e950, 0x06aa0,
+	0x0aea6, 0x0ab50, 0x04b60, 0x0aae4, 0x0a570, 0x05260, 0x0f263, 0x0d950, 0x05b57, 0x056a0,
+	0x096d0, 0x04dd5, 0x04ad0, 0x0a4d0, 0x0d4d4, 0x0d250, 0x0d558, 0x0b540, 0x0b6a0, 0x195a6,
+	0x095b0, 0x049b0, 0x0a974, 0x0a4b0, 0x0b27a, 0x06a50, 0x06d40, 0x0af46, 0x0ab60, 0x09570,
+	0x04af5, 0x04970, 0x064b0, 0x074a3, 0x0ea50, 0x06b58, 0x05ac0, 0x0ab60, 0x096d5, 0x092e0,
+	0x0c960, 0x0d954, 0x0d4a0, 0x0da50, 0x07552, 0x056a0, 0x0abb7, 0x025d0, 0x092d0, 0x0cab5,
+	0x0a950, 0x0b4a0, 0x0baa4, 0x0ad50, 0x055d9, 0x04ba0, 0x0a5b0, 0x15176, 0x052b0, 0x0a930,
+	0x07954, 0x06aa0, 0x0ad50, 0x05b52, 0x04b60, 0x0a6e6, 0x0a4e0, 0x0d260, 0x0ea65, 0x0d530,
+	0x05aa0, 0x076a3, 0x096d0, 0x04afb, 0x04ad0, 0x0a4d0, 0x1d0b6, 0x0d250, 0x0d520, 0x0dd45,
+	0x0b5a0, 0x056d0, 0x055b2, 0x049b0, 0x0a577, 0x0a4b0, 0x0aa50, 0x1b255, 0x06d20, 0x0ada0,
+	0x14b63, 0x09370, 0x049f8, 0x04970, 0x064b0, 0x168a6, 0x0ea50, 0x06b20, 0x1a6c4, 0x0aae0,
+	0x092e0, 0x0d2e3, 0x0c960, 0x0d557, 0x0d4a0, 0x0da50, 0x05d55, 0x056a0, 0x0a6d0, 0x055d4,
+	0x052d0, 0x0a9b8, 0x0a950, 0x0b4a0, 0x0b6a6, 0x0ad50, 0x055a0, 0x0aba4, 0x0a5b0, 0x052b0,
+	0x0b273, 0x06930, 0x07337, 0x06aa0, 0x0ad50, 0x14b55, 0x04b60, 0x0a570, 0x054e4, 0x0d160,
+	0x0e968, 0x0d520, 0x0daa0, 0x16aa6, 0x056d0, 0x04ae0, 0x0a9d4, 0x0a2d0, 0x0d150, 0x0f252,
+	0x0d520,
+}
+
+// 음력 1900년 1월 1일
+var lunarBaseDate = time.Date(1900, 1, 31, 0, 0, 0, 0, time.UTC)
+
+// 그 해의 윤달, 없으면 0
+func lunarLeapMonth(year int) int {
+	return lunarInfo[year-minLunarYear] & 0xf
+}
+
+// 그 해 month월의 일수, isLeap이면 윤달의 일수
+func lunarMonthDays(year, month int, isLeap bool) int {
+	info := lunarInfo[year-minLunarYear]
+	if isLeap {
+		if info&0x10000 != 0 {
+			return 30
+		}
+		return 29
+	}
+	if info&(0x10000>>month) != 0 {
+		return 30
+	}
+	return 29
+}
+
+func lunarYearDays(year int) int {
+	days := 0
+	for month := 1; month <= 12; month++ {
+		days += lunarMonthDays(year, month, false)
+	}
+	if leap := lunarLeapMonth(year); leap != 0 {
+		days += lunarMonthDays(year, leap, true)
+	}
+	return days
+}
+
+// 음력 날짜를 양력으로 변환, 범위를 벗어나면 false
+// 그 해에 없는 윤달이면 평달로, 30일이 없는 달이면 29일로 맞춤
+func lunarToSolar(year, month, day int, isLeap bool) (time.Time, bool) {
+	if year < minLunarYear || year > maxLunarYear || month < 1 || month > 12 || day < 1 || day > 30 {
+		return time.Time{}, false
+	}
+	if isLeap && lunarLeapMonth(year) != month {
+		isLeap = false
+	}
+
+	offset := 0
+	for y := minLunarYear; y < year; y++ {
+		offset += lunarYearDays(y)
+	}
+	leap := lunarLeapMonth(year)
+	for m := 1; m < month; m++ {
+		offset += lunarMonthDays(year, m, false)
+		if m == leap {
+			offset += lunarMonthDays(year, m, true)
+		}
+	}
+	// 윤달은 같은 달의 평달 다음에 옴
+	if isLeap {
+		offset += lunarMonthDays(year, month, false)
+	}
+	if monthDays := lunarMonthDays(year, month, isLeap); day > monthDays {
+		day = monthDays
+	}
+	return lunarBaseDate.AddDate(0, 0, offset+day-1), true
+}
@@ -0,0 +1,40 @@
+package controller
+
+import "testing"
+
+func TestLunarToSolar(t *testing.T) {
+	tests := []struct {
+		year, month, day int
+		isLeap bool
+		want string
+		ok bool
+	}{
+		// 설날, 추석
+		{2000, 1, 1, false, "2000-02-05", true},
+		{2024, 1, 1, false, "2024-02-10", true},
+		{2024, 8, 15, false, "2024-09-17", true},
+		// 윤달은 같은 달의 평달 다음
+		{2023, 2, 1, false, "2023-02-20", true},
+		{2023, 2, 1, true, "2023-03-22", true},
+		{2020, 4, 8, true, "2020-05-30", true},
+		// 그 해에 없는 윤달은 평달로
+		{2023, 3, 1, true, "2023-04-20", true},
+		// 29일까지인 달의 30일은 29일로
+		{2025, 6, 30, true, "2025-08-22", true},
+		// 표의 처음과 끝
+		{1900, 1, 1, false, "1900-01-31", true},
+		{2100, 12, 29, false, "2101-01-28", true},
+		// 범위 밖
+		{1899, 1, 1, false, "", false},
+		{2101, 1, 1, false, "", false},
+		{2024, 13, 1, false, "", false},
+		{2024, 1, 31, false, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := lunarToSolar(tt.year, tt.month, tt.day, tt.isLeap)
+		if ok != tt.ok || (ok && got.Format("2006-01-02") != tt.want) {
+			t.Errorf("lunarToSolar(%d, %d, %d, %v) = %s, %v, want %s, %v", tt.year, tt.month, tt.day, tt.isLeap, got.Format("2006-01-02"), ok, tt.want, tt.ok)
+		}
+	}
+}
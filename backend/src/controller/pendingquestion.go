@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDeferMinutes = 24 * 60
+	maxDeferMinutes = 7 * 24 * 60
+)
+
+// 내가 아직 답하지 않은 질문 목록, 접속 시 전달되는 순서와 같음 (?include_deferred=1이면 미룬 질문도 포함)
+func GetPendingQuestionsHandler(c *gin.Context) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	first_uuid, _, conn_id, err2 := model.GetConnectionByUsrsUUID(uuid)
+	if err2 != nil {
+		fmt.Println("ERROR #255 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	pendingQuestions, err3 := model.GetPendingQuestions(conn_id, uuid, first_uuid == uuid, getTimeNow().Format(chatTimeLayout), c.Query("include_deferred") == "1")
+	if err3 != nil {
+		fmt.Println("ERROR #256 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(pendingQuestions) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	marshaledData, err4 := json.Marshal(pendingQuestions)
+	if err4 != nil {
+		fmt.Println("ERROR #257 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 대기 중인 질문의 상태 변경, 내가 답하지 않은 질문이 아니면 404
+func updatePendingQuestion(c *gin.Context, status string, defer_until interface{}) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	questionID, err2 := strconv.Atoi(c.Param("questionID"))
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	first_uuid, _, conn_id, err3 := model.GetConnectionByUsrsUUID(uuid)
+	if err3 != nil {
+		fmt.Println("ERROR #258 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	isPending, err4 := model.UpdatePendingQuestionStatus(conn_id, questionID, uuid, first_uuid == uuid, status, defer_until)
+	if err4 != nil {
+		fmt.Println("ERROR #259 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isPending {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// 대기 중인 질문 건너뛰기, 다시 전달되지 않음 (상대는 계속 답할 수 있음)
+func SkipPendingQuestionHandler(c *gin.Context) {
+	updatePendingQuestion(c, "skipped", nil)
+}
+
+// 대기 중인 질문 미루기 (?minutes=, 기본 하루, 최대 7일), 미룬 시간이 지나면 대기열 뒤에 다시 붙음
+func DeferPendingQuestionHandler(c *gin.Context) {
+	minutes := defaultDeferMinutes
+	if c.Query("minutes") != "" {
+		n, err := strconv.Atoi(c.Query("minutes"))
+		if err != nil || n <= 0 || n > maxDeferMinutes {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		minutes = n
+	}
+	updatePendingQuestion(c, "deferred", getTimeNow().Add(time.Duration(minutes)*time.Minute).Format(chatTimeLayout))
+}
@@ -0,0 +1,519 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxQuestionLength = 255
+	maxTriggerWordLength = 255
+	maxCategoryLength = 50
+	defaultQuestionCategory = "general"
+	defaultQuestionLanguage = "ko"
+	// 여러 질문의 트리거 단어가 동시에 발견되면 priority가 높은 질문부터 보냄
+	maxQuestionPriority = 1000
+	// 객관식 질문 보기 개수와 길이
+	maxAnswerOptions = 10
+	maxAnswerOptionLength = 100
+	// 한 번에 가져올 수 있는 질문 개수와 파일 크기
+	maxImportQuestions = 1000
+	maxImportSize = 5 << 20
+)
+
+// BCP 47 형식의 언어 태그 (ko, en, en-US, zh-Hant 등)
+var languageTagRegexp = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+var categoryRegexp = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// 질문 생성/수정/가져오기 요청, is_active와 priority가 없으면 생성 시 기본값, 수정 시 기존 값 유지
+type questionRequest struct {
+	Question_contents string `json:"question_contents"`
+	Trigger_words []string `json:"trigger_words"`
+	Category string `json:"category"`
+	Language string `json:"language"`
+	Is_active *int `json:"is_active"`
+	Priority *int `json:"priority"`
+	Answer_options []string `json:"answer_options"`
+}
+
+type importError struct {
+	Row int `json:"row"`
+	Message string `json:"message"`
+}
+
+// ADMIN_TOKEN 환경변수와 X-Admin-Token 헤더가 같은지 확인, ADMIN_TOKEN이 없으면 관리자 API 비활성
+func checkAdmin(c *gin.Context) bool {
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminToken == "" {
+		c.Writer.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Token")), []byte(adminToken)) != 1 {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// 질문 요청 유효성 검사 후 저장할 QuestionData로 변환, 잘못된 경우 에러 메세지 리턴
+// 트리거 단어는 앞뒤 공백을 제거하고 대소문자 구분 없이 중복 제거
+func validateQuestion(request questionRequest, is_active, priority int) (model.QuestionData, string) {
+	questionData := model.QuestionData{
+		Question_contents: strings.TrimSpace(request.Question_contents),
+		Category: strings.ToLower(strings.TrimSpace(request.Category)),
+		Language: strings.TrimSpace(request.Language),
+		Is_active: is_active,
+		Priority: priority,
+		Trigger_words: []string{},
+	}
+	if request.Is_active != nil {
+		questionData.Is_active = *request.Is_active
+	}
+	if request.Priority != nil {
+		questionData.Priority = *request.Priority
+	}
+
+	if questionData.Question_contents == "" {
+		return questionData, "EMPTY_QUESTION"
+	}
+	if utf8.RuneCountInString(questionData.Question_contents) > maxQuestionLength {
+		return questionData, "QUESTION_TOO_LONG"
+	}
+
+	if len(request.Trigger_words) == 0 {
+		return questionData, "NO_TRIGGER_WORD"
+	}
+	isDuplicated := make(map[string]bool)
+	for _, word := range request.Trigger_words {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			return questionData, "EMPTY_TRIGGER_WORD"
+		}
+		if utf8.RuneCountInString(word) > maxTriggerWordLength {
+			return questionData, "TRIGGER_WORD_TOO_LONG"
+		}
+		if isDuplicated[strings.ToLower(word)] {
+			continue
+		}
+		isDuplicated[strings.ToLower(word)] = true
+		questionData.Trigger_words = append(questionData.Trigger_words, word)
+	}
+
+	if questionData.Category == "" {
+		questionData.Category = defaultQuestionCategory
+	}
+	if len(questionData.Category) > maxCategoryLength || !categoryRegexp.MatchString(questionData.Category) {
+		return questionData, "INVALID_CATEGORY"
+	}
+	if questionData.Language == "" {
+		questionData.Language = defaultQuestionLanguage
+	}
+	if len(questionData.Language) > 35 || !languageTagRegexp.MatchString(questionData.Language) {
+		return questionData, "INVALID_LANGUAGE"
+	}
+	if questionData.Is_active != 0 && questionData.Is_active != 1 {
+		return questionData, "INVALID_ACTIVE"
+	}
+	if questionData.Priority < -maxQuestionPriority || questionData.Priority > maxQuestionPriority {
+		return questionData, "INVALID_PRIORITY"
+	}
+
+	// 보기가 없으면 주관식, 있으면 서로 다른 보기가 2개 이상인 객관식
+	if len(request.Answer_options) > maxAnswerOptions {
+		return questionData, "TOO_MANY_OPTIONS"
+	}
+	isDuplicatedOption := make(map[string]bool)
+	for _, option := range request.Answer_options {
+		option = strings.TrimSpace(option)
+		if option == "" || option == "not-written" {
+			return questionData, "EMPTY_OPTION"
+		}
+		if utf8.RuneCountInString(option) > maxAnswerOptionLength {
+			return questionData, "OPTION_TOO_LONG"
+		}
+		if isDuplicatedOption[option] {
+			return questionData, "DUPLICATED_OPTION"
+		}
+		isDuplicatedOption[option] = true
+		questionData.Answer_options = append(questionData.Answer_options, option)
+	}
+	if len(questionData.Answer_options) == 1 {
+		return questionData, "NOT_ENOUGH_OPTIONS"
+	}
+	return questionData, ""
+}
+
+// 질문 목록 (?category=&language=&active=1|0)
+func GetQuestionsHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	filter := model.QuestionFilter{
+		Category: strings.ToLower(c.Query("category")),
+		Language: c.Query("language"),
+		Is_active: -1,
+	}
+	switch c.Query("active") {
+	case "":
+	case "1":
+		filter.Is_active = 1
+	case "0":
+		filter.Is_active = 0
+	default:
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	questions, err := model.GetQuestions(filter)
+	if err != nil {
+		fmt.Println("ERROR #224 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(questions) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	marshaledData, err2 := json.Marshal(questions)
+	if err2 != nil {
+		fmt.Println("ERROR #225 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 질문 하나 생성
+func InsertQuestionHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	var request questionRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	questionData, message := validateQuestion(request, 1, 0)
+	if message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+
+	questionIDs, err2 := model.InsertQuestions([]model.QuestionData{questionData})
+	if err2 != nil {
+		fmt.Println("ERROR #226 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	questionData.Question_id = questionIDs[0]
+	invalidateTriggerIndex()
+
+	marshaledData, err3 := json.Marshal(questionData)
+	if err3 != nil {
+		fmt.Println("ERROR #227 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 질문 내용, 트리거 단어, 카테고리, 언어 수정
+func UpdateQuestionHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	questionID, err := strconv.Atoi(c.Param("questionID"))
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var request questionRequest
+	err2 := c.ShouldBindJSON(&request)
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	existing, isExist, err3 := model.GetQuestionDataByQuestionID(questionID)
+	if err3 != nil {
+		fmt.Println("ERROR #228 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	questionData, message := validateQuestion(request, existing.Is_active, existing.Priority)
+	if message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+	questionData.Question_id = questionID
+
+	isExist, err4 := model.UpdateQuestion(questionData)
+	if err4 != nil {
+		fmt.Println("ERROR #229 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	invalidateTriggerIndex()
+
+	marshaledData, err5 := json.Marshal(questionData)
+	if err5 != nil {
+		fmt.Println("ERROR #230 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 질문 비활성화, 이미 받은 답변은 남겨둠
+func DeactivateQuestionHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	questionID, err := strconv.Atoi(c.Param("questionID"))
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	isExist, err2 := model.UpdateQuestionActive(questionID, 0)
+	if err2 != nil {
+		fmt.Println("ERROR #231 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	invalidateTriggerIndex()
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// CSV 파일을 질문 요청 목록으로 변환
+// 첫 줄은 헤더(question_contents, trigger_words, category, language, is_active, priority, answer_options), 트리거 단어와 보기는 |로 구분
+func parseQuestionCSV(data []byte) ([]questionRequest, *importError) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &importError{0, "INVALID_CSV"}
+	}
+	if len(records) == 0 {
+		return nil, &importError{0, "EMPTY_FILE"}
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["question_contents"]; !ok {
+		return nil, &importError{1, "MISSING_QUESTION_CONTENTS_COLUMN"}
+	}
+	if _, ok := columns["trigger_words"]; !ok {
+		return nil, &importError{1, "MISSING_TRIGGER_WORDS_COLUMN"}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	requests := []questionRequest{}
+	for _, record := range records[1:] {
+		request := questionRequest{
+			Question_contents: field(record, "question_contents"),
+			Category: field(record, "category"),
+			Language: field(record, "language"),
+		}
+		if triggerWords := field(record, "trigger_words"); triggerWords != "" {
+			request.Trigger_words = strings.Split(triggerWords, "|")
+		}
+		if answerOptions := field(record, "answer_options"); answerOptions != "" {
+			request.Answer_options = strings.Split(answerOptions, "|")
+		}
+		if isActive := strings.TrimSpace(field(record, "is_active")); isActive != "" {
+			// 숫자가 아니면 유효성 검사에서 INVALID_ACTIVE로 처리되도록 -1로 설정
+			n, err := strconv.Atoi(isActive)
+			if err != nil {
+				n = -1
+			}
+			request.Is_active = &n
+		}
+		if priority := strings.TrimSpace(field(record, "priority")); priority != "" {
+			n, err := strconv.Atoi(priority)
+			if err != nil {
+				n = maxQuestionPriority + 1
+			}
+			request.Priority = &n
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// 질문 여러 개를 JSON 배열 또는 CSV로 한 번에 추가
+// CSV는 multipart의 file 필드나 text/csv body로 받음, 한 줄이라도 잘못되면 아무것도 저장하지 않고 줄 번호와 에러 리턴
+func ImportQuestionsHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+(1<<20))
+
+	var requests []questionRequest
+	var importErrors []importError
+	if c.ContentType() == "application/json" {
+		err := c.ShouldBindJSON(&requests)
+		if err != nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	} else {
+		var data []byte
+		var err error
+		if c.ContentType() == "multipart/form-data" {
+			file, err2 := c.FormFile("file")
+			if err2 != nil {
+				c.Writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if file.Size > maxImportSize {
+				c.Writer.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			f, err3 := file.Open()
+			if err3 != nil {
+				fmt.Println("ERROR #232 : ", err3.Error())
+				c.Writer.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			defer f.Close()
+			data, err = io.ReadAll(f)
+		} else {
+			data, err = io.ReadAll(c.Request.Body)
+		}
+		if err != nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var csvError *importError
+		requests, csvError = parseQuestionCSV(data)
+		if csvError != nil {
+			importErrors = append(importErrors, *csvError)
+		}
+	}
+
+	if len(requests) == 0 && len(importErrors) == 0 {
+		importErrors = append(importErrors, importError{0, "EMPTY_FILE"})
+	}
+	if len(requests) > maxImportQuestions {
+		importErrors = append(importErrors, importError{0, "TOO_MANY_QUESTIONS"})
+	}
+
+	// JSON은 배열 index, CSV는 헤더를 포함한 줄 번호 기준
+	rowOffset := 1
+	if c.ContentType() != "application/json" {
+		rowOffset = 2
+	}
+	questions := []model.QuestionData{}
+	for i, request := range requests {
+		questionData, message := validateQuestion(request, 1, 0)
+		if message != "" {
+			importErrors = append(importErrors, importError{i + rowOffset, message})
+			continue
+		}
+		questions = append(questions, questionData)
+	}
+
+	if len(importErrors) > 0 {
+		marshaledData, err := json.Marshal(struct {
+			Errors []importError `json:"errors"`
+		}{importErrors})
+		if err != nil {
+			fmt.Println("ERROR #233 : ", err.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		c.Writer.Write(marshaledData)
+		return
+	}
+
+	questionIDs, err := model.InsertQuestions(questions)
+	if err != nil {
+		fmt.Println("ERROR #234 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	invalidateTriggerIndex()
+
+	sendData := struct {
+		Question_ids []int `json:"question_ids"`
+	}{questionIDs}
+
+	marshaledData, err2 := json.Marshal(sendData)
+	if err2 != nil {
+		fmt.Println("ERROR #235 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 질문 ID로만 답변을 저장하던 때 생긴 중복 answer 레코드를 합치고 unique key 추가 (관리자)
+// ?dry_run=1이면 변경하지 않고 바뀔 개수만 리턴
+func RepairAnswersHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	result, err := model.RepairAnswers(c.Query("dry_run") == "1")
+	if err != nil {
+		fmt.Println("ERROR #246 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	marshaledData, err2 := json.Marshal(result)
+	if err2 != nil {
+		fmt.Println("ERROR #247 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
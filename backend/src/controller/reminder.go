@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxReminders = 5
+	// 최대 30일 전까지 알림 가능
+	maxReminderMinutes = 30 * 24 * 60
+	reminderCheckInterval = time.Minute
+)
+
+// 알림 시간(분) 확인, 중복은 합치고 큰 것부터 정렬
+func normalizeReminders(reminders []int) ([]int, string) {
+	normalized := []int{}
+	for _, minutes := range reminders {
+		if minutes < 0 || minutes > maxReminderMinutes {
+			return nil, "INVALID_REMINDER"
+		}
+		isExist := false
+		for _, m := range normalized {
+			isExist = isExist || m == minutes
+		}
+		if !isExist {
+			normalized = append(normalized, minutes)
+		}
+	}
+	if len(normalized) > maxReminders {
+		return nil, "TOO_MANY_REMINDERS"
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(normalized)))
+	return normalized, ""
+}
+
+// 남은 시간을 "1일 2시간", "30분" 형식으로
+func remainingTimeText(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes <= 0 {
+		return "지금"
+	}
+	parts := []string{}
+	if days := minutes / (24 * 60); days > 0 {
+		parts = append(parts, fmt.Sprintf("%d일", days))
+	}
+	if hours := minutes % (24 * 60) / 60; hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d시간", hours))
+	}
+	if minutes%60 > 0 {
+		parts = append(parts, fmt.Sprintf("%d분", minutes%60))
+	}
+	return strings.Join(parts, " ") + " 후"
+}
+
+// 채팅으로 보낼 알림 내용, 남은 시간은 알림 시간이 아니라 실제로 남은 시간
+// 알림 시간이 지난 뒤에 일정을 추가했거나 서버가 멈춰있다가 늦게 보내는 경우도 맞게 표시됨
+func reminderText(anniversary model.AnniversaryData, start time.Time, loc *time.Location, now time.Time) string {
+	local := start.In(loc)
+	when := fmt.Sprintf("%d월 %d일", local.Month(), local.Day())
+	if !anniversary.Is_all_day {
+		when += local.Format(" 15:04")
+	}
+	text := fmt.Sprintf("⏰ 일정 알림: %s\n%s 시작 (%s)", anniversary.Contents, remainingTimeText(start.Sub(now)), when)
+	if anniversary.Location != "" {
+		text += "\n장소: " + anniversary.Location
+	}
+	return text
+}
+
+// 지금 알림을 보내야 하는 일정 시작 시간, 이미 알림을 보낸 시작 시간(lastFired)까지는 제외
+// 시작 시간 알림(0분 전)도 보낼 수 있도록 시작한 지 확인 간격이 안 지난 일정까지 포함
+func dueOccurrence(reminder model.ReminderData, now time.Time) (time.Time, bool) {
+	start, end, ok := eventTimes(reminder.Anniversary)
+	if !ok {
+		return time.Time{}, false
+	}
+	lastFired, err := time.ParseInLocation(chatTimeLayout, reminder.Last_fired, getTimeNow().Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	offset := time.Duration(reminder.Minutes_before) * time.Minute
+	from := now.Add(-reminderCheckInterval)
+	var due time.Time
+	for _, o := range eventOccurrences(reminder.Anniversary, start, end, eventLocation(reminder.Anniversary), from, now.Add(offset+time.Second)) {
+		if !o.start.After(from) || o.start.After(now.Add(offset)) || !o.start.After(lastFired) {
+			continue
+		}
+		if due.IsZero() || o.start.Before(due) {
+			due = o.start
+		}
+	}
+	return due, !due.IsZero()
+}
+
+// 1분마다 알림 시간이 된 일정을 확인
+// 보낸 기록은 DB에 남아서 서버를 다시 시작해도 같은 알림을 다시 보내지 않고, 멈춰있던 동안의 알림은 일정 시작 전이면 보냄
+func StartReminderScheduler() {
+	go func() {
+		ticker := time.NewTicker(reminderCheckInterval)
+		defer ticker.Stop()
+
+		for ; true; <-ticker.C {
+			sendReminders(time.Now())
+		}
+	}()
+}
+
+func sendReminders(now time.Time) {
+	storageLoc := getTimeNow().Location()
+	reminderDatas, err := model.GetDueReminders(now.Add(-reminderCheckInterval).In(storageLoc).Format(chatTimeLayout), now.In(storageLoc).Format(chatTimeLayout))
+	if err != nil {
+		fmt.Println("ERROR #307 : ", err.Error())
+		return
+	}
+
+	for _, reminderData := range reminderDatas {
+		start, ok := dueOccurrence(reminderData, now)
+		if !ok {
+			continue
+		}
+		isClaimed, err := model.ClaimReminder(reminderData.Reminder_id, reminderData.Last_fired, start.In(storageLoc).Format(chatTimeLayout))
+		if err != nil {
+			fmt.Println("ERROR #308 : ", err.Error())
+			continue
+		}
+		if !isClaimed {
+			continue
+		}
+		sendSystemMessage(reminderData.Anniversary.Connection_id, reminderText(reminderData.Anniversary, start, eventLocation(reminderData.Anniversary), now))
+	}
+}
+
+// 커플 채팅 기록에 시스템 메시지를 저장하고 접속 중인 사람에게 바로 전송
+// 접속 중이 아니면 다음 접속 때 채팅 기록으로 전달됨
+func sendSystemMessage(conn_id int, text string) {
+	chatData := model.ChatData{
+		Text_body: text,
+		Writer_id: model.SystemWriterID(conn_id),
+		Write_time: getTimeNow().Format(chatTimeLayout),
+		Is_system: 1,
+	}
+	chat_id, err := model.InsertSystemChat(conn_id, chatData.Text_body, chatData.Write_time)
+	if err != nil {
+		fmt.Println("ERROR #309 : ", err.Error())
+		return
+	}
+	chatData.Chat_id = chat_id
+
+	first_uuid, second_uuid, err2 := model.GetConnectionByConnID(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #310 : ", err2.Error())
+		return
+	}
+	for _, item := range getOnlineConns(first_uuid, second_uuid) {
+		err := item.WriteJSON([]model.ChatData{chatData})
+		if err != nil {
+			fmt.Println("ERROR #311 : ", err.Error())
+		}
+	}
+}
+
+// 일정 알림 시간 변경 (reminders: 일정 시작 몇 분 전인지, 빈 배열이면 알림 끄기)
+func UpdateAnniversaryReminderHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	anniversary_id, err2 := strconv.Atoi(c.Param("id"))
+	if err2 != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	Input := struct {
+		Reminders []int `json:"reminders"`
+	}{}
+	err3 := c.ShouldBindJSON(&Input)
+	if err3 != nil || Input.Reminders == nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	reminders, message := normalizeReminders(Input.Reminders)
+	if message != "" {
+		c.String(http.StatusBadRequest, "%v", message)
+		return
+	}
+
+	isExist, err4 := model.CheckAnniversaryByConnID(conn_id, anniversary_id)
+	if err4 != nil {
+		fmt.Println("ERROR #312 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err5 := model.ReplaceReminders(conn_id, anniversary_id, reminders)
+	if err5 != nil {
+		fmt.Println("ERROR #313 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
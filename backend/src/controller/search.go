@@ -0,0 +1,387 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 검색 결과 snippet에서 검색어 앞뒤로 보여줄 글자 수
+const snippetRadius = 30
+
+var dateRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// 검색어 하나를 원형과 조사를 뗀 형태로 확장
+// "고양이"처럼 조사와 같은 글자로 끝나는 단어도 있어서 원형도 항상 같이 검색함
+func searchVariants(term string) []string {
+	variants := []string{term}
+	for _, particle := range koreanParticles {
+		if !strings.HasSuffix(term, particle) {
+			continue
+		}
+		stem := strings.TrimSuffix(term, particle)
+		if utf8.RuneCountInString(stem) >= 2 {
+			variants = append(variants, stem)
+		}
+		break
+	}
+	return variants
+}
+
+// 검색어를 한글/영문/숫자 단위로 나눔
+// 이모지나 "^^"처럼 글자와 숫자가 하나도 없는 검색어는 공백 단위로 나눠서 그대로 검색
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !isSearchRune(r)
+	})
+	if len(terms) == 0 {
+		terms = strings.Fields(strings.ToLower(query))
+	}
+	return terms
+}
+
+func isSearchRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// 검색어들로 MATCH AGAINST boolean mode 검색식과 ngram으로 찾을 수 없는 검색어 목록을 만듦
+// 모든 검색어가 들어간 채팅만 찾고, 각 검색어는 원형이나 조사를 뗀 형태 중 하나만 있으면 됨
+func buildSearchQuery(terms []string) (string, []string) {
+	var groups []string
+	var likeTerms []string
+	for _, term := range terms {
+		// ngram 검색이 안되는 한 글자 검색어와 글자/숫자가 없는 검색어는 LIKE로 검색
+		if utf8.RuneCountInString(term) < 2 || strings.IndexFunc(term, isSearchRune) == -1 {
+			likeTerms = append(likeTerms, term)
+			continue
+		}
+		var phrases []string
+		for _, variant := range searchVariants(term) {
+			phrases = append(phrases, `"`+variant+`"`)
+		}
+		groups = append(groups, "+("+strings.Join(phrases, " ")+")")
+	}
+	return strings.Join(groups, " "), likeTerms
+}
+
+// 채팅 본문에서 처음 검색어가 나온 곳을 중심으로 snippet을 자르고, snippet 안의 검색어 위치(rune 단위)를 리턴
+func buildSnippet(text string, terms []string) (string, [][2]int) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	// 소문자로 바꾸면서 글자 수가 달라지는 특수한 문자가 있으면 위치가 어긋나므로 원문으로 비교
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	var words [][]rune
+	for _, term := range terms {
+		for _, variant := range searchVariants(term) {
+			words = append(words, []rune(variant))
+		}
+	}
+
+	first := -1
+	for i := range lower {
+		if matchLength(lower, i, words) > 0 {
+			first = i
+			break
+		}
+	}
+
+	start, end := 0, len(runes)
+	if first != -1 {
+		if first-snippetRadius > 0 {
+			start = first - snippetRadius
+		}
+		if first+snippetRadius*2 < end {
+			end = first + snippetRadius*2
+		}
+	} else if end > snippetRadius*3 {
+		end = snippetRadius * 3
+	}
+
+	highlights := [][2]int{}
+	for i := start; i < end; {
+		length := matchLength(lower, i, words)
+		if length == 0 {
+			i++
+			continue
+		}
+		highlightEnd := i + length
+		if highlightEnd > end {
+			highlightEnd = end
+		}
+		highlights = append(highlights, [2]int{i - start, highlightEnd - start})
+		i += length
+	}
+
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+		for i := range highlights {
+			highlights[i][0]++
+			highlights[i][1]++
+		}
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet, highlights
+}
+
+// text의 i 위치에서 시작하는 가장 긴 검색어 길이, 없으면 0
+func matchLength(text []rune, i int, words [][]rune) int {
+	longest := 0
+	for _, word := range words {
+		if len(word) <= longest || i+len(word) > len(text) {
+			continue
+		}
+		if string(text[i:i+len(word)]) == string(word) {
+			longest = len(word)
+		}
+	}
+	return longest
+}
+
+// 쿼리 파라미터로 검색 조건을 만듦, 잘못된 요청이면 응답을 쓰고 false 리턴
+func buildSearchFilter(c *gin.Context, query string) (model.SearchFilter, []string, bool) {
+	filter := model.SearchFilter{
+		From: c.Query("from"),
+		To: c.Query("to"),
+		Type: c.Query("type"),
+	}
+
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return filter, nil, false
+	}
+
+	first_uuid, second_uuid, _, err2 := model.GetConnectionByUsrsUUID(uuid)
+	if err2 != nil {
+		fmt.Println("ERROR #211 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return filter, nil, false
+	}
+
+	if (filter.From != "" && !dateRegexp.MatchString(filter.From)) || (filter.To != "" && !dateRegexp.MatchString(filter.To)) {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return filter, nil, false
+	}
+	if filter.Type != "" && filter.Type != "text" && filter.Type != "file" && filter.Type != "image" && filter.Type != "voice" {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return filter, nil, false
+	}
+
+	switch c.Query("sender") {
+	case "me":
+		filter.Writer_ids = []string{uuid}
+	case "partner":
+		if first_uuid == uuid {
+			filter.Writer_ids = []string{second_uuid}
+		} else {
+			filter.Writer_ids = []string{first_uuid}
+		}
+	case "":
+		filter.Writer_ids = []string{first_uuid, second_uuid}
+	default:
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return filter, nil, false
+	}
+
+	terms := searchTerms(query)
+	if len(terms) == 0 && filter.Type == "" && filter.From == "" && filter.To == "" {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return filter, nil, false
+	}
+	filter.Fulltext_query, filter.Like_terms = buildSearchQuery(terms)
+	filter.Limit, filter.Offset = getPageParams(c)
+	return filter, terms, true
+}
+
+// 채팅 검색 (?q=&sender=me|partner&from=YYYY-MM-DD&to=YYYY-MM-DD&type=text|file|image|voice&page=&limit=&context=)
+func SearchChatHandler(c *gin.Context) {
+	filter, terms, ok := buildSearchFilter(c, c.Query("q"))
+	if !ok {
+		return
+	}
+
+	results, total, err := model.SearchChats(filter)
+	if err != nil {
+		fmt.Println("ERROR #212 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(results) == 0 {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for i := range results {
+		results[i].Snippet, results[i].Highlights = buildSnippet(results[i].Text_body, terms)
+	}
+
+	coupleUUIDs, ok := getCoupleUUIDs(c)
+	if !ok {
+		return
+	}
+	err3 := attachSearchContext(results, coupleUUIDs, getSizeParam(c, "context", defaultContextSize, maxContextSize))
+	if err3 != nil {
+		fmt.Println("ERROR #218 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData := struct {
+		Total int `json:"total"`
+		Results []model.SearchData `json:"results"`
+	}{
+		total,
+		results,
+	}
+
+	marshaledData, err2 := json.Marshal(sendData)
+	if err2 != nil {
+		fmt.Println("ERROR #213 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 검색 결과 하나당 붙이는 앞뒤 대화 기본 개수와 최대 개수
+const defaultContextSize = 2
+const maxContextSize = 10
+
+// 페이지 없이 한번에 돌려주는 기존 단어 검색 API(/api/chat/word/:param)의 최대 결과 개수
+const maxWordSearchResults = 200
+
+// 채팅 기록을 불러올 때 기준 채팅 앞뒤로 불러오는 기본 개수와 최대 개수
+const defaultHistorySize = 30
+const maxHistorySize = 200
+
+// 쿼리 파라미터 값을 0~max 범위의 숫자로 변환, 없거나 잘못된 값이면 defaultValue
+func getSizeParam(c *gin.Context, key string, defaultValue, max int) int {
+	value, err := strconv.Atoi(c.Query(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// cookie의 uuid로 커플 두 사람의 uuid를 리턴, 실패하면 응답을 쓰고 false 리턴
+func getCoupleUUIDs(c *gin.Context) ([]string, bool) {
+	return getCoupleWriterIDs(c, false)
+}
+
+// 커플 채팅의 작성자들, includeSystem이면 커플에게 보낸 시스템 메시지(일정 알림)의 작성자도 포함
+func getCoupleWriterIDs(c *gin.Context, includeSystem bool) ([]string, bool) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	first_uuid, second_uuid, conn_id, err2 := model.GetConnectionByUsrsUUID(uuid)
+	if err2 != nil {
+		fmt.Println("ERROR #214 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if includeSystem {
+		return []string{first_uuid, second_uuid, model.SystemWriterID(conn_id)}, true
+	}
+	return []string{first_uuid, second_uuid}, true
+}
+
+// 검색 결과마다 앞뒤 size개의 대화를 붙임, 보낸사람 필터와 상관없이 두 사람의 대화를 모두 포함
+func attachSearchContext(results []model.SearchData, coupleUUIDs []string, size int) error {
+	if size == 0 {
+		return nil
+	}
+	for i := range results {
+		previousChats, nextChats, err := model.GetChatsAroundChatID(coupleUUIDs, results[i].Chat_id, size, size)
+		if err != nil {
+			return err
+		}
+		results[i].Context = append(previousChats, nextChats...)
+	}
+	return nil
+}
+
+// chatID를 기준으로 앞뒤 채팅 기록(시스템 메시지 포함) 불러오기, 검색 결과에서 해당 채팅 위치로 이동할 때 사용 (?before=&after=)
+func GetChatHistoryHandler(c *gin.Context) {
+	coupleUUIDs, ok := getCoupleWriterIDs(c, true)
+	if !ok {
+		return
+	}
+
+	chatID, err := strconv.Atoi(c.Param("chatID"))
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	before := getSizeParam(c, "before", defaultHistorySize, maxHistorySize)
+	after := getSizeParam(c, "after", defaultHistorySize, maxHistorySize)
+
+	isExist, err2 := model.CheckChatByWriterIDs(coupleUUIDs, chatID)
+	if err2 != nil {
+		fmt.Println("ERROR #215 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isExist {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// 더 불러올 채팅이 남았는지 알기 위해 양쪽으로 하나씩 더 불러옴
+	previousChats, nextChats, err3 := model.GetChatsAroundChatID(coupleUUIDs, chatID, before+1, after+1)
+	if err3 != nil {
+		fmt.Println("ERROR #216 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	hasMoreBefore := len(previousChats) > before
+	if hasMoreBefore {
+		previousChats = previousChats[1:]
+	}
+	hasMoreAfter := len(nextChats) > after+1
+	if hasMoreAfter {
+		nextChats = nextChats[:after+1]
+	}
+
+	sendData := struct {
+		AnchorID int `json:"anchor_id"`
+		Chats []model.ChatData `json:"chats"`
+		HasMoreBefore bool `json:"has_more_before"`
+		HasMoreAfter bool `json:"has_more_after"`
+	}{
+		chatID,
+		append(previousChats, nextChats...),
+		hasMoreBefore,
+		hasMoreAfter,
+	}
+
+	marshaledData, err4 := json.Marshal(sendData)
+	if err4 != nil {
+		fmt.Println("ERROR #217 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		fulltext string
+		like []string
+	}{
+		{"사진", `+("사진")`, nil},
+		{"고양이가 밥", `+("고양이가" "고양이")`, []string{"밥"}},
+		// 글자나 숫자가 없는 검색어는 LIKE로 검색
+		{"^^", "", []string{"^^"}},
+		{"😂 ㅋ", "", []string{"ㅋ"}},
+		{"🥰 ^^", "", []string{"🥰", "^^"}},
+		{"", "", nil},
+	}
+
+	for _, tt := range tests {
+		fulltext, like := buildSearchQuery(searchTerms(tt.query))
+		if fulltext != tt.fulltext || !reflect.DeepEqual(like, tt.like) {
+			t.Errorf("buildSearchQuery(%q) = %q, %#v, want %q, %#v", tt.query, fulltext, like, tt.fulltext, tt.like)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 점수 합을 -1 ~ 1로 줄일 때 쓰는 값, 클수록 단어 하나의 영향이 작아짐
+	sentimentNormalizeAlpha = 15
+	// 이보다 점수가 크거나 작아야 긍정/부정 채팅으로 셈
+	sentimentThreshold = 0.05
+	intensifierWeight = 1.5
+)
+
+// 감정 사전, 토크나이저를 거친 단어의 점수
+var sentimentLexicon = func() map[string]float64 {
+	lexicon := make(map[string]float64)
+	for _, line := range readDictionary("sentiment.txt") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		score, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		lexicon[fields[0]] = score
+	}
+	return lexicon
+}()
+
+// 다음 단어의 점수를 반대로 바꾸는 단어
+var negationWords = map[string]bool{
+	"안": true, "못": true, "not": true, "no": true, "never": true, "dont": true, "didnt": true, "cant": true, "isnt": true,
+}
+
+// 다음 단어의 점수를 키우는 단어
+var intensifierWords = map[string]bool{
+	"너무": true, "진짜": true, "정말": true, "완전": true, "엄청": true, "되게": true, "넘": true, "very": true, "so": true, "really": true,
+}
+
+// 감정 점수를 매길 때는 부정어와 강조어도 필요해서 불용어를 빼지 않음
+var sentimentTokenizer = koreanTokenizer{includeEnglish: true, includeEmoji: true}
+
+// 자음/모음으로 쓰는 웃음과 울음 표현 (ㅋㅋ, ㅎㅎ, ㅠㅠ, ㅜㅜ)
+func jamoSentiment(text string) float64 {
+	score := 0.0
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		if j-i >= 2 {
+			switch runes[i] {
+			case 'ㅋ', 'ㅎ':
+				score += 1
+			case 'ㅠ', 'ㅜ':
+				score -= 1.5
+			}
+		}
+		i = j
+	}
+	return score
+}
+
+// 사전 기반 감정 점수, -1(부정) ~ 1(긍정)
+// "안 좋아", "좋지 않아"처럼 부정어가 붙으면 반대로, "너무 좋아"처럼 강조어가 붙으면 크게 셈
+func scoreSentiment(text string) float64 {
+	tokens := sentimentTokenizer.Tokenize(text)
+	sum := jamoSentiment(text)
+	isNegated := false
+	weight := 1.0
+	for i, token := range tokens {
+		if negationWords[token] {
+			isNegated = true
+			continue
+		}
+		if intensifierWords[token] {
+			weight = intensifierWeight
+			continue
+		}
+		score, ok := sentimentLexicon[token]
+		if !ok {
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1] == "않다" {
+			isNegated = !isNegated
+		}
+		if isNegated {
+			score = -score
+		}
+		sum += score * weight
+		isNegated = false
+		weight = 1.0
+	}
+	if sum == 0 {
+		return 0
+	}
+	return math.Round(sum/math.Sqrt(sum*sum+sentimentNormalizeAlpha)*1000) / 1000
+}
+
+// 텍스트 채팅의 감정 점수를 저장, 커플이 감정 분석을 껐으면 저장하지 않음
+func recordSentiment(conn_id int, chatData model.ChatData) {
+	isEnabled, err := model.IsSentimentEnabled(conn_id)
+	if err != nil {
+		fmt.Println("ERROR #283 : ", err.Error())
+		return
+	}
+	if !isEnabled {
+		return
+	}
+
+	err = model.InsertChatSentiment(chatData.Chat_id, conn_id, chatData.Writer_id, chatData.Write_time, scoreSentiment(chatData.Text_body))
+	if err != nil {
+		fmt.Println("ERROR #284 : ", err.Error())
+	}
+}
+
+// 하루 동안 한 사람의 감정 요약
+type moodStats struct {
+	Avg_score float64 `json:"avg_score"`
+	Messages int `json:"messages"`
+	Positive int `json:"positive"`
+	Negative int `json:"negative"`
+	scoreSum float64
+}
+
+type dailyMood struct {
+	Date string `json:"date"`
+	Me moodStats `json:"me"`
+	Partner moodStats `json:"partner"`
+}
+
+// 날짜별 두 사람의 평균 감정 점수 (?from=&to=&tz=), 기간이 없으면 최근 30일
+// 감정 분석을 끈 커플은 403 MOOD_DISABLED
+func GetMoodTrendHandler(c *gin.Context) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	from, to, loc, ok := getStatsRange(c)
+	if !ok || to.Sub(from) > maxCalendarDays*24*time.Hour {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	conn_id, err2 := model.SelectConnIDByUUID(uuid)
+	if err2 != nil {
+		fmt.Println("ERROR #285 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	isEnabled, err3 := model.IsSentimentEnabled(conn_id)
+	if err3 != nil {
+		fmt.Println("ERROR #286 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !isEnabled {
+		c.String(http.StatusForbidden, "%v", "MOOD_DISABLED")
+		return
+	}
+
+	storageLoc := getTimeNow().Location()
+	hourlySentiments, err4 := model.GetHourlySentiments(conn_id, from.In(storageLoc).Format(chatTimeLayout), to.In(storageLoc).Format(chatTimeLayout), sentimentThreshold)
+	if err4 != nil {
+		fmt.Println("ERROR #287 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData := []dailyMood{}
+	dayIndex := make(map[string]int)
+	for day := from.In(loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		dayIndex[day.Format("2006-01-02")] = len(sendData)
+		sendData = append(sendData, dailyMood{Date: day.Format("2006-01-02")})
+	}
+
+	// 시간 단위 점수를 요청한 시간대의 날짜로 변환해서 합산
+	for _, hourly := range hourlySentiments {
+		t, err := time.ParseInLocation(chatTimeLayout, hourly.Hour, storageLoc)
+		if err != nil {
+			continue
+		}
+		i, ok := dayIndex[t.In(loc).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		mood := &sendData[i].Partner
+		if hourly.Writer_id == uuid {
+			mood = &sendData[i].Me
+		}
+		mood.scoreSum += hourly.Score_sum
+		mood.Messages += hourly.Messages
+		mood.Positive += hourly.Positive
+		mood.Negative += hourly.Negative
+	}
+	for i := range sendData {
+		for _, mood := range []*moodStats{&sendData[i].Me, &sendData[i].Partner} {
+			if mood.Messages > 0 {
+				mood.Avg_score = math.Round(mood.scoreSum/float64(mood.Messages)*1000) / 1000
+			}
+		}
+	}
+
+	marshaledData, err5 := json.Marshal(sendData)
+	if err5 != nil {
+		fmt.Println("ERROR #288 : ", err5.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 감정 분석 설정 불러오기
+func GetMoodSettingHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	isEnabled, err2 := model.IsSentimentEnabled(conn_id)
+	if err2 != nil {
+		fmt.Println("ERROR #289 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData := struct {
+		Is_enabled int `json:"is_enabled"`
+	}{0}
+	if isEnabled {
+		sendData.Is_enabled = 1
+	}
+
+	marshaledData, err3 := json.Marshal(sendData)
+	if err3 != nil {
+		fmt.Println("ERROR #290 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
+
+// 감정 분석 켜기/끄기, 끄면 지금까지 저장된 감정 점수도 삭제
+func UpdateMoodSettingHandler(c *gin.Context) {
+	conn_id, err := GetConnIDByCookie(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	Input := struct {
+		Is_enabled *int `json:"is_enabled"`
+	}{}
+	err2 := c.ShouldBindJSON(&Input)
+	if err2 != nil || Input.Is_enabled == nil || (*Input.Is_enabled != 0 && *Input.Is_enabled != 1) {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err3 := model.UpdateSentimentEnabled(conn_id, *Input.Is_enabled)
+	if err3 != nil {
+		fmt.Println("ERROR #291 : ", err3.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.WriteHeader(http.StatusOK)
+}
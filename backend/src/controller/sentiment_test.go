@@ -0,0 +1,42 @@
+package controller
+
+import "testing"
+
+func TestScoreSentiment(t *testing.T) {
+	tests := map[string]float64{
+		"": 0,
+		"오늘 회의 있어": 0,
+		"좋아": 0.459,
+		"사랑해": 0.612,
+		"너무 좋아": 0.612,
+		"안 좋아": -0.459,
+		"좋지 않아": -0.459,
+		"힘들어": -0.459,
+		"ㅋㅋㅋ": 0.25,
+		"ㅠㅠ": -0.361,
+		"행복해 ㅎㅎ": 0.718,
+		"really good": 0.612,
+		"not good": -0.459,
+		"사랑해 😍": 0.84,
+	}
+
+	for text, want := range tests {
+		if got := scoreSentiment(text); got != want {
+			t.Errorf("scoreSentiment(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+// 부정어는 점수를 뒤집고 강조어는 키우기만 함
+func TestScoreSentimentModifiers(t *testing.T) {
+	base := scoreSentiment("좋아")
+	if got := scoreSentiment("안 좋아"); got != -base {
+		t.Errorf("negated score = %v, want %v", got, -base)
+	}
+	if got := scoreSentiment("정말 좋아"); got <= base {
+		t.Errorf("intensified score = %v, want more than %v", got, base)
+	}
+	if got := scoreSentiment("정말 안 좋아"); got >= 0 {
+		t.Errorf("intensified negated score = %v, want negative", got)
+	}
+}
@@ -0,0 +1,309 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 기간을 보내지 않았을 때 통계 기간 (오늘 포함 30일)
+	defaultStatsDays = 30
+	// 이보다 오래 걸린 답장은 대화가 끊겼다가 다시 시작한 것으로 보고 응답 시간에서 뺌
+	maxResponseGap = 6 * time.Hour
+	// 같은 기간 통계를 다시 요청하면 채팅을 다시 읽지 않고 캐시된 값을 보냄
+	statsCacheTTL = 5 * time.Minute
+	maxStatsCacheSize = 1000
+	topEmojiNum = 10
+	mostActiveHourNum = 3
+)
+
+// 한 사람의 기간 안 채팅 통계
+type partnerStats struct {
+	Messages int `json:"messages"`
+	Texts int `json:"texts"`
+	Images int `json:"images"`
+	Voices int `json:"voices"`
+	Files int `json:"files"`
+	Emojis int `json:"emojis"`
+	// 상대 채팅에 답장한 횟수와 평균 걸린 시간(초)
+	Responses int `json:"responses"`
+	Avg_response_seconds int `json:"avg_response_seconds"`
+	responseTotal time.Duration
+}
+
+type dailyStats struct {
+	Date string `json:"date"`
+	Me int `json:"me"`
+	Partner int `json:"partner"`
+}
+
+type hourStats struct {
+	Hour int `json:"hour"`
+	Count int `json:"count"`
+}
+
+type emojiStats struct {
+	Emoji string `json:"emoji"`
+	Count int `json:"count"`
+}
+
+type streakStats struct {
+	Days int `json:"days"`
+	From string `json:"from,omitempty"`
+	To string `json:"to,omitempty"`
+}
+
+type attachmentStats struct {
+	Images int `json:"images"`
+	Voices int `json:"voices"`
+	Files int `json:"files"`
+	Total int `json:"total"`
+}
+
+type relationshipStats struct {
+	From string `json:"from"`
+	To string `json:"to"`
+	Timezone string `json:"timezone"`
+	Total_messages int `json:"total_messages"`
+	Me partnerStats `json:"me"`
+	Partner partnerStats `json:"partner"`
+	Daily []dailyStats `json:"daily"`
+	Hourly []hourStats `json:"hourly"`
+	Most_active_hours []int `json:"most_active_hours"`
+	Longest_streak streakStats `json:"longest_streak"`
+	Current_streak int `json:"current_streak"`
+	Top_emojis []emojiStats `json:"top_emojis"`
+	Attachments attachmentStats `json:"attachments"`
+}
+
+type statsCacheEntry struct {
+	stats relationshipStats
+	builtAt time.Time
+}
+
+var (
+	statsCache = make(map[string]statsCacheEntry)
+	statsCacheMutex sync.Mutex
+)
+
+func getCachedStats(key string) (relationshipStats, bool) {
+	statsCacheMutex.Lock()
+	defer statsCacheMutex.Unlock()
+
+	entry, ok := statsCache[key]
+	if !ok || time.Since(entry.builtAt) >= statsCacheTTL {
+		return relationshipStats{}, false
+	}
+	return entry.stats, true
+}
+
+func setCachedStats(key string, stats relationshipStats) {
+	statsCacheMutex.Lock()
+	defer statsCacheMutex.Unlock()
+
+	// 캐시가 너무 커지면 오래된 것부터 고르지 않고 전부 비움
+	if len(statsCache) >= maxStatsCacheSize {
+		statsCache = make(map[string]statsCacheEntry)
+	}
+	statsCache[key] = statsCacheEntry{stats, time.Now()}
+}
+
+// 기간 파라미터, from이 없으면 오늘 포함 최근 30일
+func getStatsRange(c *gin.Context) (time.Time, time.Time, *time.Location, bool) {
+	if c.Query("from") != "" {
+		return getRangeParams(c)
+	}
+	loc, ok := getTimezoneParam(c)
+	if !ok {
+		return time.Time{}, time.Time{}, nil, false
+	}
+	now := getTimeNow().In(loc)
+	// 하루 동안은 같은 기간이 되도록 내일 0시까지로 맞춤
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
+	return to.AddDate(0, 0, -defaultStatsDays), to, loc, true
+}
+
+// 시간순으로 정렬된 채팅으로 통계 계산, 날짜와 시간은 loc 기준
+func computeRelationshipStats(myUUID string, chats []model.ChatData, from, to time.Time, loc *time.Location) relationshipStats {
+	stats := relationshipStats{
+		From: from.In(loc).Format(chatTimeLayout),
+		To: to.In(loc).Format(chatTimeLayout),
+		Timezone: loc.String(),
+		Total_messages: len(chats),
+		Daily: []dailyStats{},
+		Hourly: []hourStats{},
+		Most_active_hours: []int{},
+		Top_emojis: []emojiStats{},
+	}
+
+	storageLoc := getTimeNow().Location()
+	dayIndex := make(map[string]int)
+	for day := from.In(loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		dayIndex[day.Format("2006-01-02")] = len(stats.Daily)
+		stats.Daily = append(stats.Daily, dailyStats{Date: day.Format("2006-01-02")})
+	}
+	hourly := make([]int, 24)
+	emojiCounts := make(map[string]int)
+
+	var prevWriter string
+	var prevTime time.Time
+	for _, chat := range chats {
+		writeTime, err := time.ParseInLocation(chatTimeLayout, chat.Write_time, storageLoc)
+		if err != nil {
+			continue
+		}
+		local := writeTime.In(loc)
+
+		partner := &stats.Partner
+		if chat.Writer_id == myUUID {
+			partner = &stats.Me
+		}
+		partner.Messages++
+		if i, ok := dayIndex[local.Format("2006-01-02")]; ok {
+			if chat.Writer_id == myUUID {
+				stats.Daily[i].Me++
+			} else {
+				stats.Daily[i].Partner++
+			}
+		}
+		hourly[local.Hour()]++
+
+		switch {
+		case chat.Is_file == 0:
+			partner.Texts++
+			for _, emoji := range extractEmojis(chat.Text_body) {
+				partner.Emojis++
+				emojiCounts[emoji]++
+			}
+		case chat.Is_image == 1:
+			partner.Images++
+		case chat.Is_voice == 1:
+			partner.Voices++
+		default:
+			partner.Files++
+		}
+
+		// 상대가 보낸 채팅 다음에 처음 보낸 채팅을 답장으로 봄
+		if prevWriter != "" && prevWriter != chat.Writer_id {
+			if gap := writeTime.Sub(prevTime); gap >= 0 && gap <= maxResponseGap {
+				partner.Responses++
+				partner.responseTotal += gap
+			}
+		}
+		prevWriter, prevTime = chat.Writer_id, writeTime
+	}
+
+	for _, partner := range []*partnerStats{&stats.Me, &stats.Partner} {
+		if partner.Responses > 0 {
+			partner.Avg_response_seconds = int((partner.responseTotal / time.Duration(partner.Responses)).Seconds())
+		}
+	}
+	stats.Attachments = attachmentStats{
+		Images: stats.Me.Images + stats.Partner.Images,
+		Voices: stats.Me.Voices + stats.Partner.Voices,
+		Files: stats.Me.Files + stats.Partner.Files,
+	}
+	stats.Attachments.Total = stats.Attachments.Images + stats.Attachments.Voices + stats.Attachments.Files
+
+	activeHours := []hourStats{}
+	for hour, count := range hourly {
+		stats.Hourly = append(stats.Hourly, hourStats{hour, count})
+		if count > 0 {
+			activeHours = append(activeHours, hourStats{hour, count})
+		}
+	}
+	sort.SliceStable(activeHours, func(i, j int) bool {
+		return activeHours[i].Count > activeHours[j].Count
+	})
+	for i := 0; i < len(activeHours) && i < mostActiveHourNum; i++ {
+		stats.Most_active_hours = append(stats.Most_active_hours, activeHours[i].Hour)
+	}
+
+	for emoji, count := range emojiCounts {
+		stats.Top_emojis = append(stats.Top_emojis, emojiStats{emoji, count})
+	}
+	sort.Slice(stats.Top_emojis, func(i, j int) bool {
+		if stats.Top_emojis[i].Count != stats.Top_emojis[j].Count {
+			return stats.Top_emojis[i].Count > stats.Top_emojis[j].Count
+		}
+		return stats.Top_emojis[i].Emoji < stats.Top_emojis[j].Emoji
+	})
+	if len(stats.Top_emojis) > topEmojiNum {
+		stats.Top_emojis = stats.Top_emojis[:topEmojiNum]
+	}
+
+	// 둘 중 한 명이라도 채팅한 날이 이어진 일수
+	streak := 0
+	for i, day := range stats.Daily {
+		if day.Me+day.Partner == 0 {
+			streak = 0
+			continue
+		}
+		streak++
+		if streak > stats.Longest_streak.Days {
+			stats.Longest_streak = streakStats{streak, stats.Daily[i-streak+1].Date, day.Date}
+		}
+	}
+	// 기간 마지막 날부터 거꾸로 센 연속 일수, 마지막 날이 오늘이고 아직 채팅이 없으면 어제부터 셈
+	last := len(stats.Daily) - 1
+	if last >= 0 && stats.Daily[last].Me+stats.Daily[last].Partner == 0 && stats.Daily[last].Date == getTimeNow().In(loc).Format("2006-01-02") {
+		last--
+	}
+	for i := last; i >= 0 && stats.Daily[i].Me+stats.Daily[i].Partner > 0; i-- {
+		stats.Current_streak++
+	}
+	return stats
+}
+
+// 커플 채팅 통계 (?from=&to=&tz=), 기간이 없으면 최근 30일
+// 날짜별/사람별 채팅 수, 평균 답장 시간, 많이 채팅한 시간대, 연속 채팅 일수, 이모지, 첨부파일 수
+func GetRelationshipStatsHandler(c *gin.Context) {
+	uuid, err := model.CookieExist(c)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	from, to, loc, ok := getStatsRange(c)
+	if !ok || to.Sub(from) > maxCalendarDays*24*time.Hour {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%s|%d|%d|%s", uuid, from.Unix(), to.Unix(), loc.String())
+	stats, isCached := getCachedStats(cacheKey)
+	if !isCached {
+		first_uuid, second_uuid, _, err2 := model.GetConnectionByUsrsUUID(uuid)
+		if err2 != nil {
+			fmt.Println("ERROR #280 : ", err2.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		storageLoc := getTimeNow().Location()
+		chats, err3 := model.GetChatsForStats([]string{first_uuid, second_uuid}, from.In(storageLoc).Format(chatTimeLayout), to.In(storageLoc).Format(chatTimeLayout))
+		if err3 != nil {
+			fmt.Println("ERROR #281 : ", err3.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		stats = computeRelationshipStats(uuid, chats, from, to, loc)
+		setCachedStats(cacheKey, stats)
+	}
+
+	marshaledData, err4 := json.Marshal(stats)
+	if err4 != nil {
+		fmt.Println("ERROR #282 : ", err4.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strconv"
+)
+
+// 썸네일을 저장하는 디렉토리, 원본은 assets에 저장됨
+const thumbnailDir = "assets/thumbnails"
+
+// 썸네일을 만들 수 있는 최대 픽셀 수 (가로×세로), 이보다 큰 이미지는 디코딩하지 않음
+const maxThumbnailPixels = 50000000
+
+// 업로드 시 생성하는 썸네일 가로 길이 목록 (px)
+var thumbnailWidths = []int{160, 320, 640}
+
+// ?size= 쿼리로 요청 가능한 썸네일인지 확인
+func isThumbnailWidth(width int) bool {
+	for _, w := range thumbnailWidths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// 썸네일 파일 경로, 투명도를 유지해야하는 png/gif는 png로, 나머지는 jpg로 저장
+func thumbnailPath(chatID, width int, format string) string {
+	ext := ".jpg"
+	if format == "png" || format == "gif" {
+		ext = ".png"
+	}
+	return thumbnailDir + "/" + strconv.Itoa(chatID) + "-" + strconv.Itoa(width) + ext
+}
+
+// 이미지 원본에서 위치정보(EXIF GPS)를 제거한 바이트를 리턴
+// jpeg/png 외의 포맷이나 EXIF가 없는 이미지는 그대로 리턴
+func stripLocationMetadata(data []byte) []byte {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return stripJPEGLocation(data)
+	}
+	if bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return stripPNGLocation(data)
+	}
+	return data
+}
+
+// jpeg의 APP1(Exif) 세그먼트를 찾아서 GPS IFD를 지움
+func stripJPEGLocation(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	for _, tiff := range jpegExifSegments(out) {
+		clearGPSIFD(tiff)
+	}
+	return out
+}
+
+// jpeg에서 APP1(Exif) 세그먼트들의 TIFF 부분을 리턴, 리턴한 slice는 data를 그대로 가리킴
+func jpegExifSegments(data []byte) [][]byte {
+	var segments [][]byte
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			break
+		}
+		marker := data[i+1]
+		// SOS 이후는 이미지 데이터이므로 더 볼 필요 없음
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		segLen := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if segLen < 2 || i+2+segLen > len(data) {
+			break
+		}
+		seg := data[i+4 : i+2+segLen]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			segments = append(segments, seg[6:])
+		}
+		i += 2 + segLen
+	}
+	return segments
+}
+
+// png의 eXIf 청크는 통째로 제거 (png에서는 회전 정보를 거의 쓰지 않음)
+func stripPNGLocation(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:8]...)
+
+	i := 8
+	for i+12 <= len(data) {
+		chunkLen := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + chunkLen
+		if chunkLen < 0 || end > len(data) {
+			return data
+		}
+		if string(data[i+4:i+8]) != "eXIf" {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return append(out, data[i:]...)
+}
+
+// TIFF 구조의 EXIF에서 IFD0의 GPSInfo(0x8825) 태그가 가리키는 GPS IFD를 0으로 채움
+// 엔트리 수가 0이 되므로 리더들은 빈 GPS IFD로 읽게 되고, 회전 등 나머지 EXIF 정보는 유지됨
+func clearGPSIFD(tiff []byte) {
+	if len(tiff) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	ifd0 := int(order.Uint32(tiff[4:8]))
+	if ifd0+2 > len(tiff) {
+		return
+	}
+	entries := int(order.Uint16(tiff[ifd0 : ifd0+2]))
+
+	gpsOffset := 0
+	for n := 0; n < entries; n++ {
+		entry := ifd0 + 2 + n*12
+		if entry+12 > len(tiff) {
+			return
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x8825 {
+			gpsOffset = int(order.Uint32(tiff[entry+8 : entry+12]))
+			break
+		}
+	}
+	if gpsOffset == 0 || gpsOffset+2 > len(tiff) {
+		return
+	}
+
+	gpsEntries := int(order.Uint16(tiff[gpsOffset : gpsOffset+2]))
+	for n := 0; n < gpsEntries; n++ {
+		entry := gpsOffset + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		// 값이 4바이트를 넘으면 offset이 가리키는 실제 데이터(위도/경도 등)도 지움
+		size := exifTypeSize(order.Uint16(tiff[entry+2:entry+4])) * int(order.Uint32(tiff[entry+4:entry+8]))
+		if size > 4 {
+			valueOffset := int(order.Uint32(tiff[entry+8 : entry+12]))
+			if valueOffset >= 0 && valueOffset+size <= len(tiff) {
+				zeroBytes(tiff[valueOffset : valueOffset+size])
+			}
+		}
+		zeroBytes(tiff[entry : entry+12])
+	}
+	zeroBytes(tiff[gpsOffset : gpsOffset+2])
+}
+
+// jpeg EXIF IFD0의 Orientation(0x0112) 값, 없거나 잘못된 값이면 1(회전 없음)
+func jpegOrientation(data []byte) int {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return 1
+	}
+	for _, tiff := range jpegExifSegments(data) {
+		if len(tiff) < 8 {
+			continue
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			continue
+		}
+
+		ifd0 := int(order.Uint32(tiff[4:8]))
+		if ifd0+2 > len(tiff) {
+			continue
+		}
+		entries := int(order.Uint16(tiff[ifd0 : ifd0+2]))
+		for n := 0; n < entries; n++ {
+			entry := ifd0 + 2 + n*12
+			if entry+12 > len(tiff) {
+				break
+			}
+			if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+				orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+				if orientation >= 1 && orientation <= 8 {
+					return orientation
+				}
+				return 1
+			}
+		}
+	}
+	return 1
+}
+
+func exifTypeSize(t uint16) int {
+	switch t {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	}
+	return 0
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// 이미지를 디코딩해서 thumbnailWidths 크기별로 썸네일 생성, gif는 첫 프레임만 사용
+func generateThumbnails(chatID int, data []byte) error {
+	// 작은 파일이라도 선언한 크기가 매우 크면 디코딩할 때 메모리를 많이 쓰므로 헤더만 먼저 확인
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxThumbnailPixels {
+		return fmt.Errorf("image too large to thumbnail: %dx%d", config.Width, config.Height)
+	}
+
+	var src image.Image
+	var format string
+
+	if bytes.HasPrefix(data, []byte("GIF8")) {
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err == nil && len(g.Image) > 0 {
+			src = gifFirstFrame(g)
+			format = "gif"
+		}
+	} else {
+		src, format, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		return fmt.Errorf("empty image")
+	}
+	// 썸네일에는 EXIF가 없으므로 회전 정보를 미리 적용해서 저장
+	rgba := applyOrientation(toRGBA(src), jpegOrientation(data))
+
+	err = os.MkdirAll(thumbnailDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, width := range thumbnailWidths {
+		thumbnail := resizeImage(rgba, width)
+
+		var buf bytes.Buffer
+		if format == "png" || format == "gif" {
+			err = png.Encode(&buf, thumbnail)
+		} else {
+			err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 80})
+		}
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(thumbnailPath(chatID, width, format), buf.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gif의 첫 프레임은 전체 화면 크기보다 작을 수 있어서 논리적 화면 크기에 맞춰 그려줌
+func gifFirstFrame(g *gif.GIF) image.Image {
+	frame := g.Image[0]
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		return frame
+	}
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+	return canvas
+}
+
+// 픽셀 배열(Pix)을 바로 읽고 쓸 수 있도록 RGBA로 변환, 이미 RGBA면 그대로 사용
+// jpeg의 YCbCr, gif의 Paletted 등은 draw.Draw가 형식별로 한 번에 변환함
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// EXIF Orientation(1~8)에 맞게 이미지를 뒤집거나 회전, 5~8은 가로세로가 바뀜
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		d := dst.PixOffset(0, y)
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 좌우 반전
+				sx, sy = w-1-x, y
+			case 3: // 180도 회전
+				sx, sy = w-1-x, h-1-y
+			case 4: // 상하 반전
+				sx, sy = x, h-1-y
+			case 5: // 좌상단-우하단 대각선 기준 반전
+				sx, sy = y, x
+			case 6: // 시계방향 90도 회전
+				sx, sy = y, h-1-x
+			case 7: // 우상단-좌하단 대각선 기준 반전
+				sx, sy = w-1-y, h-1-x
+			case 8: // 반시계방향 90도 회전
+				sx, sy = w-1-y, x
+			}
+			s := src.PixOffset(b.Min.X+sx, b.Min.Y+sy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+			d += 4
+		}
+	}
+	return dst
+}
+
+// 가로 길이를 width에 맞추고 비율을 유지해서 축소 (box filter)
+// 원본이 width보다 작으면 확대하지 않고 원본 그대로 리턴
+func resizeImage(src *image.RGBA, width int) *image.RGBA {
+	b := src.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW <= width {
+		return src
+	}
+
+	height := srcH * width / srcW
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*srcH/height
+		y1 := b.Min.Y + (y+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		d := dst.PixOffset(0, y)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*srcW/width
+			x1 := b.Min.X + (x+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// RGBA는 알파가 곱해진 값이라 채널별로 그대로 평균내면 됨
+			var r, g, bl, a uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					bl += uint64(row[i+2])
+					a += uint64(row[i+3])
+				}
+			}
+			count := uint64((x1 - x0) * (y1 - y0))
+			dst.Pix[d] = uint8(r / count)
+			dst.Pix[d+1] = uint8(g / count)
+			dst.Pix[d+2] = uint8(bl / count)
+			dst.Pix[d+3] = uint8(a / count)
+			d += 4
+		}
+	}
+	return dst
+}
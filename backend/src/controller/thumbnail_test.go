@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// 회전 정보(Orientation)와 위도 값(GPSLatitude) 하나가 들어간 EXIF TIFF
+// IFD0는 8, GPS IFD는 38, 위도 값은 56에서 시작
+func testExifTIFF(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 80)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+
+	order.PutUint16(tiff[8:], 2)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	order.PutUint16(tiff[22:], 0x8825)
+	order.PutUint16(tiff[24:], 4)
+	order.PutUint32(tiff[26:], 1)
+	order.PutUint32(tiff[30:], 38)
+
+	order.PutUint16(tiff[38:], 1)
+	order.PutUint16(tiff[40:], 0x0002)
+	order.PutUint16(tiff[42:], 5)
+	order.PutUint32(tiff[44:], 3)
+	order.PutUint32(tiff[48:], 56)
+	for i := 56; i < 80; i++ {
+		tiff[i] = byte(i)
+	}
+	return tiff
+}
+
+func testJPEG(tiff []byte) []byte {
+	data := []byte{0xFF, 0xD8}
+	if tiff != nil {
+		segment := append([]byte("Exif\x00\x00"), tiff...)
+		data = append(data, 0xFF, 0xE1, byte((len(segment)+2)>>8), byte(len(segment)+2))
+		data = append(data, segment...)
+	}
+	return append(data, 0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9)
+}
+
+func TestStripJPEGLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		tiff []byte
+		orientation int
+	}{
+		{"little endian", testExifTIFF(binary.LittleEndian, 6), 6},
+		{"big endian", testExifTIFF(binary.BigEndian, 3), 3},
+		{"without exif", nil, 1},
+	}
+
+	for _, tt := range tests {
+		data := testJPEG(tt.tiff)
+		original := append([]byte{}, data...)
+		stripped := stripLocationMetadata(data)
+
+		if !bytes.Equal(data, original) {
+			t.Errorf("%s: input was modified", tt.name)
+		}
+		if len(stripped) != len(data) {
+			t.Errorf("%s: length changed from %d to %d", tt.name, len(data), len(stripped))
+		}
+		if got := jpegOrientation(stripped); got != tt.orientation {
+			t.Errorf("%s: orientation = %d, want %d", tt.name, got, tt.orientation)
+		}
+		if tt.tiff == nil {
+			if !bytes.Equal(stripped, data) {
+				t.Errorf("%s: data changed", tt.name)
+			}
+			continue
+		}
+
+		// GPS IFD와 위도 값만 지워지고 나머지는 그대로
+		tiff := stripped[12 : 12+len(tt.tiff)]
+		for i := range tiff {
+			cleared := i >= 38
+			if cleared && tiff[i] != 0 {
+				t.Errorf("%s: GPS byte %d = %d, want 0", tt.name, i, tiff[i])
+				break
+			}
+			if !cleared && tiff[i] != tt.tiff[i] {
+				t.Errorf("%s: byte %d changed", tt.name, i)
+				break
+			}
+		}
+	}
+}
+
+func TestStripPNGLocation(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	clean := buf.Bytes()
+
+	// IHDR(8 + 25byte) 바로 뒤에 eXIf 청크 삽입
+	exif := testExifTIFF(binary.BigEndian, 1)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(exif)))
+	chunk = append(chunk, "eXIf"...)
+	chunk = append(chunk, exif...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	withExif := append(append(append([]byte{}, clean[:33]...), chunk...), clean[33:]...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"with exif", withExif, clean},
+		{"without exif", clean, clean},
+		{"not an image", []byte("plain text"), []byte("plain text")},
+	}
+
+	for _, tt := range tests {
+		if got := stripLocationMetadata(tt.data); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: stripLocationMetadata = % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+	// 왼쪽 빨강, 오른쪽 파랑인 2x1 이미지
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		width, height int
+		first color.RGBA
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{4, 2, 1, red},
+		{5, 1, 2, red},
+		{6, 1, 2, red},
+		{7, 1, 2, blue},
+		{8, 1, 2, blue},
+		{0, 2, 1, red},
+	}
+
+	for _, tt := range tests {
+		dst := applyOrientation(src, tt.orientation)
+		b := dst.Bounds()
+		if b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.width, tt.height)
+			continue
+		}
+		if got := color.RGBAModel.Convert(dst.At(b.Min.X, b.Min.Y)); got != tt.first {
+			t.Errorf("orientation %d: first pixel = %v, want %v", tt.orientation, got, tt.first)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"embed"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 단어 랭킹에 사용하는 형태소 사전, 한 줄에 하나씩 적고 #으로 시작하는 줄은 주석
+//go:embed dict/*.txt
+var dictFS embed.FS
+
+var (
+	koreanParticles = loadDictionary("particles.txt")
+	koreanEndings = loadDictionary("endings.txt")
+	koreanNouns = dictionarySet("nouns.txt")
+	koreanVerbs = dictionarySet("verbs.txt")
+	defaultStopwords = dictionarySet("stopwords.txt")
+)
+
+// 채팅 텍스트를 랭킹에 집계할 단어들로 나누는 방법
+// WORD_TOKENIZER 환경변수로 고르고, 바꾼 뒤에는 /api/admin/wordcount/rebuild로 예전 채팅을 다시 집계해야 함
+type wordTokenizer interface {
+	Tokenize(text string) []string
+}
+
+var (
+	currentWordTokenizer wordTokenizer
+	wordTokenizerOnce sync.Once
+)
+
+// WORD_TOKENIZER=simple이면 예전처럼 한글 덩어리를 그대로, 아니면 조사/어미를 떼는 한국어 토크나이저 사용
+// WORD_TOKENIZER_ENGLISH=1, WORD_TOKENIZER_EMOJI=1이면 영어 단어와 이모지도 집계
+func getWordTokenizer() wordTokenizer {
+	wordTokenizerOnce.Do(func() {
+		if os.Getenv("WORD_TOKENIZER") == "simple" {
+			currentWordTokenizer = simpleTokenizer{}
+			return
+		}
+		currentWordTokenizer = koreanTokenizer{
+			includeEnglish: envInt("WORD_TOKENIZER_ENGLISH", 0) == 1,
+			includeEmoji: envInt("WORD_TOKENIZER_EMOJI", 0) == 1,
+			stopwords: defaultStopwords,
+		}
+	})
+	return currentWordTokenizer
+}
+
+func readDictionary(name string) []string {
+	data, err := dictFS.ReadFile("dict/" + name)
+	if err != nil {
+		panic(err)
+	}
+
+	words := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words
+}
+
+// 뒤에서부터 떼어내는 사전, 긴 것부터 확인하도록 정렬
+func loadDictionary(name string) []string {
+	words := readDictionary(name)
+	sort.SliceStable(words, func(i, j int) bool {
+		return utf8.RuneCountInString(strings.Fields(words[i])[0]) > utf8.RuneCountInString(strings.Fields(words[j])[0])
+	})
+	return words
+}
+
+func dictionarySet(name string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range readDictionary(name) {
+		set[word] = true
+	}
+	return set
+}
+
+func isHangul(r rune) bool {
+	return r >= '가' && r <= '힣'
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F300 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF)
+}
+
+// 이모지 뒤에 붙어 하나의 이모지를 이루는 글자 (피부색, 변형 선택자, ZWJ)
+func isEmojiModifier(r rune) bool {
+	return (r >= 0x1F3FB && r <= 0x1F3FF) || r == 0xFE0F || r == 0x200D
+}
+
+// runes[start]에서 시작하는 이모지 하나가 끝나는 위치
+func emojiEnd(runes []rune, start int) int {
+	i := start + 1
+	for i < len(runes) && isEmojiModifier(runes[i]) {
+		// ZWJ 뒤의 이모지까지 붙여야 👨‍👩‍👧 같은 이모지가 하나로 집계됨
+		if runes[i] == 0x200D && i+1 < len(runes) && isEmoji(runes[i+1]) {
+			i++
+		}
+		i++
+	}
+	return i
+}
+
+// 텍스트에 나온 이모지들
+func extractEmojis(text string) []string {
+	emojis := []string{}
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isEmoji(runes[i]) {
+			i++
+			continue
+		}
+		end := emojiEnd(runes, i)
+		emojis = append(emojis, string(runes[i:end]))
+		i = end
+	}
+	return emojis
+}
+
+// 한글 덩어리만 그대로 단어로 보는 예전 방식
+type simpleTokenizer struct{}
+
+func (simpleTokenizer) Tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !isHangul(r)
+	})
+}
+
+// 사전 기반으로 조사와 어미를 떼서 "사랑해", "사랑해요", "사랑을"을 모두 "사랑"으로 집계하는 토크나이저
+type koreanTokenizer struct {
+	includeEnglish bool
+	includeEmoji bool
+	stopwords map[string]bool
+}
+
+func (t koreanTokenizer) Tokenize(text string) []string {
+	tokens := []string{}
+	add := func(token string) {
+		if token != "" && !t.stopwords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		start := i
+		switch {
+		case isHangul(runes[i]):
+			for i < len(runes) && isHangul(runes[i]) {
+				i++
+			}
+			add(stemKorean(string(runes[start:i])))
+		case t.includeEnglish && runes[i] < utf8.RuneSelf && unicode.IsLetter(runes[i]):
+			for i < len(runes) && runes[i] < utf8.RuneSelf && (unicode.IsLetter(runes[i]) || runes[i] == '\'') {
+				i++
+			}
+			// 한 글자 영어는 단어로 보지 않음
+			if word := strings.ToLower(strings.Trim(string(runes[start:i]), "'")); utf8.RuneCountInString(word) >= 2 {
+				add(strings.ReplaceAll(word, "'", ""))
+			}
+		case t.includeEmoji && isEmoji(runes[i]):
+			i = emojiEnd(runes, i)
+			add(string(runes[start:i]))
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// 한글 단어 하나를 원형으로 바꿈
+// 사전에 있는 명사는 그대로, 용언은 어미를 떼고 "다"를 붙인 원형, 나머지는 조사를 뗀 형태
+func stemKorean(word string) string {
+	if koreanNouns[word] {
+		return word
+	}
+
+	for _, line := range koreanEndings {
+		fields := strings.Fields(line)
+		if !strings.HasSuffix(word, fields[0]) {
+			continue
+		}
+		stem := strings.TrimSuffix(word, fields[0])
+		if len(fields) > 1 {
+			stem += fields[1]
+		}
+		if koreanVerbs[stem] {
+			return stem + "다"
+		}
+		// "사랑하다", "공부하다" 같은 명사+하다는 명사로 집계
+		if strings.HasSuffix(stem, "하") {
+			if noun := strings.TrimSuffix(stem, "하"); noun != "" {
+				return noun
+			}
+			return stem
+		}
+	}
+
+	for _, particle := range koreanParticles {
+		if !strings.HasSuffix(word, particle) {
+			continue
+		}
+		// "바나나", "원숭이"처럼 조사와 같은 글자로 끝나는 명사가 많아서
+		// 사전에 없는 단어는 두 글자 이상인 조사만 뗌
+		stem := strings.TrimSuffix(word, particle)
+		if koreanNouns[stem] || (utf8.RuneCountInString(stem) >= 2 && utf8.RuneCountInString(particle) >= 2) {
+			return stem
+		}
+		break
+	}
+	return word
+}
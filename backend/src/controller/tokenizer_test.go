@@ -0,0 +1,35 @@
+package controller
+
+import "testing"
+
+func TestStemKorean(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// 조사와 같은 글자로 끝나는 명사는 그대로
+		{"바나나", "바나나"},
+		{"원숭이", "원숭이"},
+		{"고양이", "고양이"},
+		// 사전에 없는 단어는 한 글자 조사를 떼지 않음
+		{"바나나를", "바나나를"},
+		{"원숭이가", "원숭이가"},
+		// 사전에 있는 명사는 한 글자 조사도 뗌
+		{"고양이가", "고양이"},
+		{"강아지를", "강아지"},
+		// 두 글자 이상인 조사는 사전에 없어도 뗌
+		{"바나나에서", "바나나"},
+		{"원숭이한테", "원숭이"},
+		// 한 글자 단어에서는 조사를 떼지 않음
+		{"나는", "나는"},
+		// 용언은 어미를 떼고 원형으로, 명사+하다는 명사로
+		{"먹었어요", "먹다"},
+		{"사랑했어요", "사랑"},
+	}
+
+	for _, tt := range tests {
+		if got := stemKorean(tt.word); got != tt.want {
+			t.Errorf("stemKorean(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
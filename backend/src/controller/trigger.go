@@ -0,0 +1,307 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+)
+
+const (
+	// DB에서 직접 질문을 바꾼 경우도 반영되도록 트리거 인덱스를 주기적으로 다시 만듦
+	triggerIndexTTL = 5 * time.Minute
+	defaultQuestionCooldownMinutes = 30
+	defaultQuestionDailyLimit = 3
+)
+
+// 트리거 단어 뒤에 붙어도 같은 단어로 보는 조사
+var triggerParticles = func() map[string]bool {
+	particles := make(map[string]bool)
+	for _, particle := range koreanParticles {
+		particles[particle] = true
+	}
+	return particles
+}()
+
+type triggerPattern struct {
+	length int
+	question *model.QuestionData
+}
+
+type triggerNode struct {
+	children map[rune]int
+	fail int
+	patterns []int
+}
+
+// 활성화된 질문들의 트리거 단어로 만든 Aho-Corasick 오토마톤
+// 채팅마다 질문 테이블 전체를 도는 대신 채팅 길이에 비례한 시간으로 모든 트리거 단어를 찾음
+type triggerIndex struct {
+	nodes []triggerNode
+	patterns []triggerPattern
+	builtAt time.Time
+}
+
+var (
+	currentTriggerIndex *triggerIndex
+	triggerIndexMutex sync.Mutex
+	// 두 사람의 채팅이 동시에 들어와도 쿨다운과 하루 제한을 넘지 않도록 커플마다 잠금
+	questionLocks sync.Map
+)
+
+func lockQuestion(conn_id int) *sync.Mutex {
+	lock, _ := questionLocks.LoadOrStore(conn_id, &sync.Mutex{})
+	m := lock.(*sync.Mutex)
+	m.Lock()
+	return m
+}
+
+func normalizeTriggerRune(r rune) rune {
+	return unicode.ToLower(r)
+}
+
+func isTriggerWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+func buildTriggerIndex(questions []model.QuestionData) *triggerIndex {
+	index := &triggerIndex{
+		nodes: []triggerNode{{children: make(map[rune]int)}},
+		builtAt: time.Now(),
+	}
+
+	for i := range questions {
+		for _, word := range questions[i].Trigger_words {
+			runes := []rune(word)
+			node := 0
+			for _, r := range runes {
+				r = normalizeTriggerRune(r)
+				next, ok := index.nodes[node].children[r]
+				if !ok {
+					next = len(index.nodes)
+					index.nodes = append(index.nodes, triggerNode{children: make(map[rune]int)})
+					index.nodes[node].children[r] = next
+				}
+				node = next
+			}
+			if node == 0 {
+				continue
+			}
+			index.nodes[node].patterns = append(index.nodes[node].patterns, len(index.patterns))
+			index.patterns = append(index.patterns, triggerPattern{len(runes), &questions[i]})
+		}
+	}
+
+	// BFS로 실패 링크를 연결하고, 실패 링크 쪽에서 끝나는 단어도 같이 찾도록 patterns를 합침
+	queue := []int{}
+	for _, child := range index.nodes[0].children {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for r, child := range index.nodes[node].children {
+			fail := index.nodes[node].fail
+			for {
+				if next, ok := index.nodes[fail].children[r]; ok && next != child {
+					index.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					index.nodes[child].fail = 0
+					break
+				}
+				fail = index.nodes[fail].fail
+			}
+			index.nodes[child].patterns = append(index.nodes[child].patterns, index.nodes[index.nodes[child].fail].patterns...)
+			queue = append(queue, child)
+		}
+	}
+	return index
+}
+
+// 트리거 단어가 단어 단위로 들어있는지 확인
+// 앞은 단어의 시작이어야 하고, 뒤는 단어가 끝나거나 조사만 붙어 있어야 함 ("영화" -> "영화를" O, "영화관" X)
+func isTriggerBoundary(text []rune, start, end int) bool {
+	if start > 0 && isTriggerWordRune(text[start-1]) {
+		return false
+	}
+	tokenEnd := end
+	for tokenEnd < len(text) && isTriggerWordRune(text[tokenEnd]) {
+		tokenEnd++
+	}
+	return tokenEnd == end || triggerParticles[string(text[end:tokenEnd])]
+}
+
+// 채팅에서 발견된 트리거 단어의 질문들을 priority가 높은 순으로 중복 없이 리턴
+func (index *triggerIndex) match(textBody string) []*model.QuestionData {
+	text := []rune(textBody)
+	isMatched := make(map[int]bool)
+	matched := []*model.QuestionData{}
+
+	node := 0
+	for i, r := range text {
+		r = normalizeTriggerRune(r)
+		for {
+			if next, ok := index.nodes[node].children[r]; ok {
+				node = next
+				break
+			}
+			if node == 0 {
+				break
+			}
+			node = index.nodes[node].fail
+		}
+		for _, p := range index.nodes[node].patterns {
+			pattern := index.patterns[p]
+			if isMatched[pattern.question.Question_id] || !isTriggerBoundary(text, i+1-pattern.length, i+1) {
+				continue
+			}
+			isMatched[pattern.question.Question_id] = true
+			matched = append(matched, pattern.question)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Priority != matched[j].Priority {
+			return matched[i].Priority > matched[j].Priority
+		}
+		return matched[i].Question_id < matched[j].Question_id
+	})
+	return matched
+}
+
+// 캐시된 트리거 인덱스를 리턴, 없거나 오래됐으면 활성화된 질문으로 다시 만듦
+func getTriggerIndex() (*triggerIndex, error) {
+	triggerIndexMutex.Lock()
+	defer triggerIndexMutex.Unlock()
+
+	if currentTriggerIndex != nil && time.Since(currentTriggerIndex.builtAt) < triggerIndexTTL {
+		return currentTriggerIndex, nil
+	}
+	questions, err := model.GetQuestions(model.QuestionFilter{Is_active: 1})
+	if err != nil {
+		return nil, err
+	}
+	currentTriggerIndex = buildTriggerIndex(questions)
+	return currentTriggerIndex, nil
+}
+
+// 관리자가 질문을 바꾸면 다음 채팅부터 바로 반영되도록 캐시 삭제
+func invalidateTriggerIndex() {
+	triggerIndexMutex.Lock()
+	currentTriggerIndex = nil
+	triggerIndexMutex.Unlock()
+}
+
+// 0 이상의 정수 환경변수, 없거나 잘못된 값이면 기본값
+func envInt(key string, defaultValue int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n < 0 {
+		return defaultValue
+	}
+	return n
+}
+
+// 커플에게 새 질문을 보낼 수 있는지 확인
+// 마지막 질문 후 QUESTION_COOLDOWN_MINUTES분이 지나야 하고, 하루에 QUESTION_DAILY_LIMIT개까지만 보냄 (0이면 제한 없음)
+func canSendQuestion(conn_id int) (bool, error) {
+	now := getTimeNow()
+	todayCount, lastDate, err := model.GetQuestionCountSince(conn_id, now.Format("2006-01-02")+" 00:00:00")
+	if err != nil {
+		return false, err
+	}
+
+	dailyLimit := envInt("QUESTION_DAILY_LIMIT", defaultQuestionDailyLimit)
+	if dailyLimit > 0 && todayCount >= dailyLimit {
+		return false, nil
+	}
+
+	cooldown := time.Duration(envInt("QUESTION_COOLDOWN_MINUTES", defaultQuestionCooldownMinutes)) * time.Minute
+	if lastDate != "" && cooldown > 0 {
+		lastTime, err := time.ParseInLocation(chatTimeLayout, lastDate, now.Location())
+		if err == nil && now.Sub(lastTime) < cooldown {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// 질문을 클라이언트에 보내는 채팅 형식으로 변환, 클라이언트는 Is_answer가 1이면 답변 입력창을 띄움
+// 객관식 질문이면 보기도 같이 보냄
+func questionChat(question_id int, question_contents string, answer_options []string) []model.ChatData {
+	questiondata := model.ChatData{
+		Text_body: question_contents,
+		Writer_id: "question",
+		Write_time: getTimeNow().Format(chatTimeLayout),
+		Is_answer: 1,
+		Is_deleted: 0,
+		Is_file: 0,
+		Chat_id: 0,
+		Question_id: question_id,
+		Answer_options: answer_options,
+	}
+	return []model.ChatData{questiondata}
+}
+
+// 채팅 중 트리거 단어가 발견되면 단어 관련된 질문을 커플에게 던지는 기능
+// 여러 질문이 걸리면 priority가 높은 것 중 아직 하지 않은 질문 하나만 보냄
+func sendQuestion(chatData []model.ChatData, conn_id int, target_conn []*wsConn) {
+	// 일반 텍스트 채팅에서만 질문을 찾음
+	if chatData[0].Is_answer == 1 || chatData[0].Is_deleted == 1 || chatData[0].Is_file == 1 {
+		return
+	}
+
+	index, err := getTriggerIndex()
+	if err != nil {
+		fmt.Println("ERROR #44 : ", err.Error())
+		return
+	}
+	matched := index.match(chatData[0].Text_body)
+	if len(matched) == 0 {
+		return
+	}
+
+	m := lockQuestion(conn_id)
+	defer m.Unlock()
+
+	isAllowed, err := canSendQuestion(conn_id)
+	if err != nil {
+		fmt.Println("ERROR #236 : ", err.Error())
+		return
+	}
+	if !isAllowed {
+		return
+	}
+
+	for _, question := range matched {
+		// 이전에 했던 질문이면 다음 순위 질문 확인
+		isExist, err := model.CheckAnswerByConnIDandQuestionID(conn_id, question.Question_id)
+		if err != nil {
+			fmt.Println("ERROR #45 : ", err.Error())
+			return
+		}
+		if isExist {
+			continue
+		}
+
+		questiondatas := questionChat(question.Question_id, question.Question_contents, question.Answer_options)
+		for _, item := range target_conn {
+			err := item.WriteJSON(questiondatas)
+			if err != nil {
+				fmt.Println("ERROR #46 : ", err.Error())
+			}
+		}
+		// answer에 답 적기 (는 UpgradeHandler의 READ에서 처리)
+		err = model.InsertAnswer(questiondatas[0].Write_time, conn_id, question.Question_id)
+		if err != nil {
+			fmt.Println("ERROR #42 : ", err.Error())
+		}
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+)
+
+func TestTriggerIndexMatch(t *testing.T) {
+	index := buildTriggerIndex([]model.QuestionData{
+		{Question_id: 1, Trigger_words: []string{"영화"}, Priority: 1},
+		{Question_id: 2, Trigger_words: []string{"영화관"}, Priority: 5},
+		{Question_id: 3, Trigger_words: []string{"she"}},
+		{Question_id: 4, Trigger_words: []string{"he", "hers"}},
+		{Question_id: 5, Trigger_words: []string{"여행", "Travel"}, Priority: 1},
+	})
+
+	// 찾은 질문 id들, priority가 높은 순
+	tests := map[string]string{
+		"영화 보자": "[1]",
+		"영화를 보자": "[1]",
+		"영화관 가자": "[2]",
+		"영화영화": "[]",
+		"영화관에서 영화 볼래": "[2 1]",
+		// 실패 링크로 이어지는 단어도 단어 경계를 확인
+		"SHE said": "[3]",
+		"hers": "[4]",
+		"ushers": "[]",
+		"he and she": "[3 4]",
+		// 같은 질문의 트리거가 여러 개 나와도 한번만
+		"travel 여행 가자": "[5]",
+		"여행travel": "[]",
+		"": "[]",
+	}
+
+	for text, want := range tests {
+		ids := []int{}
+		for _, question := range index.match(text) {
+			ids = append(ids, question.Question_id)
+		}
+		if got := fmt.Sprint(ids); got != want {
+			t.Errorf("match(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+)
+
+// 업로드 가능한 파일 종류, 클라이언트가 보낸 Content-Type이 아니라 파일 내용으로 판별한 MIME 타입 기준
+var allowedMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png": true,
+	"image/gif": true,
+	"image/webp": true,
+	"image/bmp": true,
+	"video/mp4": true,
+	"video/webm": true,
+	"video/avi": true,
+	"audio/mpeg": true,
+	"audio/wave": true,
+	"audio/aiff": true,
+	"audio/basic": true,
+	"audio/midi": true,
+	"application/ogg": true,
+	"application/pdf": true,
+	"application/zip": true,
+	"application/x-gzip": true,
+	"application/x-rar-compressed": true,
+	"text/plain": true,
+}
+
+// 종류별 기본 업로드 용량 제한 (MB), UPLOAD_LIMIT_IMAGE_MB 같은 환경변수로 변경 가능
+var defaultUploadLimitMB = map[string]int64{
+	"image": 20,
+	"video": 300,
+	"audio": 30,
+	"text": 5,
+	"application": 50,
+}
+
+// 커플당 기본 저장공간 (MB), STORAGE_QUOTA_MB 환경변수로 변경 가능
+const defaultStorageQuotaMB = 2048
+
+// 파일 앞부분의 magic byte로 MIME 타입 판별, charset 같은 파라미터는 제거
+func detectMimeType(data []byte) string {
+	mimeType := http.DetectContentType(data)
+	if i := strings.Index(mimeType, ";"); i != -1 {
+		mimeType = mimeType[:i]
+	}
+	mimeType = strings.TrimSpace(mimeType)
+
+	// ID3 태그 없이 프레임으로 바로 시작하는 mp3는 DetectContentType이 판별하지 못함
+	if mimeType == "application/octet-stream" && isMP3FrameSync(data) {
+		if _, ok := parseMP3Frame(data); ok {
+			return "audio/mpeg"
+		}
+	}
+	return mimeType
+}
+
+// MIME 타입의 대분류, ogg 컨테이너는 음성파일로 취급
+func mimeCategory(mimeType string) string {
+	if mimeType == "application/ogg" {
+		return "audio"
+	}
+	return strings.SplitN(mimeType, "/", 2)[0]
+}
+
+func envMB(key string, defaultMB int64) int64 {
+	mb, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || mb <= 0 {
+		mb = defaultMB
+	}
+	return mb << 20
+}
+
+// 종류별 업로드 용량 제한 (byte)
+func uploadSizeLimit(category string) int64 {
+	return envMB("UPLOAD_LIMIT_"+strings.ToUpper(category)+"_MB", defaultUploadLimitMB[category])
+}
+
+// 모든 종류 중 가장 큰 업로드 용량 제한, request body 크기 제한에 사용
+func maxUploadSizeLimit() int64 {
+	var max int64
+	for category := range defaultUploadLimitMB {
+		if limit := uploadSizeLimit(category); limit > max {
+			max = limit
+		}
+	}
+	return max
+}
+
+// 커플당 저장공간 제한 (byte)
+func storageQuota() int64 {
+	return envMB("STORAGE_QUOTA_MB", defaultStorageQuotaMB)
+}
+
+// 경로 이동(../)이나 제어문자가 들어간 파일이름을 저장 가능한 이름으로 변경
+func sanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(name)
+
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+
+	// 파일시스템 이름 길이 제한 때문에 확장자를 유지하면서 자름 (chatID 접두사 자리 확보)
+	const maxLength = 200
+	if len(name) > maxLength {
+		ext := filepath.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		runes := []rune(strings.TrimSuffix(name, ext))
+		for len(string(runes))+len(ext) > maxLength {
+			runes = runes[:len(runes)-1]
+		}
+		name = string(runes) + ext
+	}
+
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// 파일 종류, 종류별 용량 제한, 커플 저장공간을 확인해서 저장 가능하면 StatusOK와 판별한 MIME 타입을 리턴
+// head는 파일 앞부분(최소 512byte 권장), size는 전체 파일 크기
+func checkUploadAllowed(conn_id int, head []byte, size int64) (string, int, string, error) {
+	// 클라이언트가 보낸 Content-Type은 믿지 않고 파일 내용으로 종류 판별
+	mimeType := detectMimeType(head)
+	if !allowedMimeTypes[mimeType] {
+		return mimeType, http.StatusUnsupportedMediaType, "UNSUPPORTED_TYPE", nil
+	}
+	if size > uploadSizeLimit(mimeCategory(mimeType)) {
+		return mimeType, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", nil
+	}
+
+	usedBytes, _, err := model.GetStorageUsageByConnID(conn_id)
+	if err != nil {
+		return mimeType, http.StatusInternalServerError, "", err
+	}
+	if usedBytes+size > storageQuota() {
+		return mimeType, http.StatusRequestEntityTooLarge, "QUOTA_EXCEEDED", nil
+	}
+	return mimeType, http.StatusOK, "", nil
+}
+
+// 파일 채팅과 첨부파일 레코드를 저장하고, 파일을 저장할 assets 경로를 리턴
+// checkUploadAllowed 이후에 다른 파일이 먼저 저장되어 저장공간 제한을 넘게 되면 false
+func insertFileChat(uuid string, conn_id int, fileName, mimeType string, size int64) (int, string, bool, error) {
+	err := os.MkdirAll("assets", 0755)
+	if err != nil {
+		return 0, "", false, err
+	}
+
+	isImage := 0
+	if mimeCategory(mimeType) == "image" {
+		isImage = 1
+	}
+
+	chatID, isSaved, err := model.InsertFileChatWithinQuota(uuid, getTimeNow().Format(chatTimeLayout), isImage, model.AttachmentData{
+		Connection_id: conn_id,
+		File_name: fileName,
+		Mime_type: mimeType,
+		File_size: size,
+	}, storageQuota())
+	if err != nil || !isSaved {
+		return 0, "", false, err
+	}
+
+	return chatID, "assets/" + strconv.Itoa(chatID) + "-" + fileName, true, nil
+}
+
+// 업로드 내용을 조각 파일 디렉토리에 임시로 저장하고 경로를 리턴, 레코드를 저장한 뒤 moveUploadedFile로 이동
+// 중간에 실패해서 남은 임시 파일은 cleanExpiredUploads가 정리함
+func writeTempUpload(r io.Reader) (string, error) {
+	err := os.MkdirAll(uploadDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(uploadDir, "*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// 저장해둔 파일을 assets 경로로 이동, 실패하면 파일 없이 남지 않도록 채팅과 첨부파일 레코드를 삭제
+// src 파일은 그대로 두므로 필요하면 호출한 쪽에서 지움
+func moveUploadedFile(src, filePath string, chatID int) error {
+	err := os.Rename(src, filePath)
+	if err == nil {
+		return nil
+	}
+	if err2 := model.DeleteAttachmentByChatID(chatID); err2 != nil {
+		fmt.Println("ERROR #316 : ", err2.Error())
+	}
+	if err3 := model.DeleteChatByChatID(chatID); err3 != nil {
+		fmt.Println("ERROR #316 : ", err3.Error())
+	}
+	return err
+}
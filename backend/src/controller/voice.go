@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 음성 메시지 말풍선에 그리는 파형 막대 개수와 막대 최대값
+const waveformBars = 64
+const waveformMax = 100
+
+// 음성 파일을 분석해서 재생 길이(ms)와 waveformBars개로 줄인 파형(0~waveformMax)을 리턴
+// wav는 PCM 샘플을 직접 읽고, mp3/ogg는 디코딩 없이 프레임 정보로 음량을 추정함
+func analyzeAudio(data []byte) (int, []int, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("RIFF")) && len(data) >= 12 && string(data[8:12]) == "WAVE":
+		return analyzeWAV(data)
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return analyzeOgg(data)
+	case bytes.HasPrefix(data, []byte("ID3")) || isMP3FrameSync(data):
+		return analyzeMP3(data)
+	}
+	return 0, nil, fmt.Errorf("unsupported audio format")
+}
+
+// 음량 값들을 waveformBars개 구간으로 나눠서 구간별 최대값을 0~waveformMax로 정규화
+// floor가 true면 최소값을 0으로 맞춤 (추정값은 무음에서도 0이 아니기 때문)
+func downsampleWaveform(levels []float64, floor bool) []int {
+	waveform := make([]int, waveformBars)
+	if len(levels) == 0 {
+		return waveform
+	}
+
+	bars := make([]float64, waveformBars)
+	for i, level := range levels {
+		bar := i * waveformBars / len(levels)
+		if level > bars[bar] {
+			bars[bar] = level
+		}
+	}
+	// 음량 값보다 막대가 많으면 비어있는 막대는 앞 막대 값으로 채움
+	if len(levels) < waveformBars {
+		for i := 1; i < waveformBars; i++ {
+			if bars[i] == 0 {
+				bars[i] = bars[i-1]
+			}
+		}
+	}
+
+	min, max := bars[0], bars[0]
+	for _, bar := range bars {
+		min = math.Min(min, bar)
+		max = math.Max(max, bar)
+	}
+	if !floor {
+		min = 0
+	}
+	if max-min <= 0 {
+		return waveform
+	}
+	for i, bar := range bars {
+		waveform[i] = int(math.Round((bar - min) / (max - min) * waveformMax))
+	}
+	return waveform
+}
+
+// PCM(정수 8/16/24/32bit, float 32bit) wav 분석
+func analyzeWAV(data []byte) (int, []int, error) {
+	var format, channels, bitsPerSample uint16
+	var sampleRate uint32
+	var samples []byte
+
+	i := 12
+	for i+8 <= len(data) {
+		chunkID := string(data[i : i+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		body := i + 8
+		end := body + chunkSize
+		if chunkSize < 0 || end > len(data) {
+			// 녹음 중 끊긴 파일은 data 청크 크기가 실제보다 클 수 있어서 남은 부분만 사용
+			end = len(data)
+		}
+
+		switch chunkID {
+		case "fmt ":
+			if end-body < 16 {
+				return 0, nil, fmt.Errorf("invalid wav fmt chunk")
+			}
+			format = binary.LittleEndian.Uint16(data[body : body+2])
+			channels = binary.LittleEndian.Uint16(data[body+2 : body+4])
+			sampleRate = binary.LittleEndian.Uint32(data[body+4 : body+8])
+			bitsPerSample = binary.LittleEndian.Uint16(data[body+14 : body+16])
+			// WAVE_FORMAT_EXTENSIBLE은 sub format의 앞 2byte가 실제 포맷
+			if format == 0xFFFE && end-body >= 26 {
+				format = binary.LittleEndian.Uint16(data[body+24 : body+26])
+			}
+		case "data":
+			samples = data[body:end]
+		}
+		// 청크는 2byte 단위로 정렬됨
+		i = end + chunkSize%2
+	}
+
+	if channels == 0 || sampleRate == 0 || samples == nil {
+		return 0, nil, fmt.Errorf("invalid wav header")
+	}
+	if (format != 1 && format != 3) || bitsPerSample%8 != 0 || bitsPerSample == 0 || bitsPerSample > 32 || (format == 3 && bitsPerSample != 32) {
+		return 0, nil, fmt.Errorf("unsupported wav encoding")
+	}
+
+	bytesPerSample := int(bitsPerSample / 8)
+	blockAlign := bytesPerSample * int(channels)
+	frames := len(samples) / blockAlign
+	durationMs := int(int64(frames) * 1000 / int64(sampleRate))
+
+	// 모든 샘플을 읽지 않고 구간당 최대 256개씩만 읽어서 peak 계산
+	levels := make([]float64, 0, waveformBars)
+	step := frames / (waveformBars * 256)
+	if step < 1 {
+		step = 1
+	}
+	for bar := 0; bar < waveformBars && frames > 0; bar++ {
+		start := bar * frames / waveformBars
+		end := (bar + 1) * frames / waveformBars
+		peak := 0.0
+		for f := start; f < end; f += step {
+			offset := f * blockAlign
+			peak = math.Max(peak, math.Abs(pcmSample(samples[offset:offset+bytesPerSample], format)))
+		}
+		levels = append(levels, peak)
+	}
+
+	return durationMs, downsampleWaveform(levels, false), nil
+}
+
+// little endian PCM 샘플 하나를 -1~1 값으로 변환
+func pcmSample(b []byte, format uint16) float64 {
+	switch len(b) {
+	case 1:
+		return (float64(b[0]) - 128) / 128
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case 3:
+		v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+		return float64(v) / 8388608
+	case 4:
+		if format == 3 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+	}
+	return 0
+}
+
+var mp3Bitrates = map[bool][3][16]int{
+	// MPEG1 : layer I, II, III
+	true: {
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	// MPEG2, 2.5 : layer I, II, III
+	false: {
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+var mp3SampleRates = [3]int{44100, 48000, 32000}
+
+func isMP3FrameSync(data []byte) bool {
+	return len(data) >= 4 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 && data[1]&0x06 != 0
+}
+
+type mp3Frame struct {
+	length int
+	samples int
+	sampleRate int
+	level float64
+}
+
+// mp3 프레임 헤더 파싱, layer III는 side info의 global_gain으로 프레임 음량 추정
+func parseMP3Frame(data []byte) (mp3Frame, bool) {
+	var frame mp3Frame
+	if !isMP3FrameSync(data) {
+		return frame, false
+	}
+
+	versionBits := (data[1] >> 3) & 0x03
+	layerBits := (data[1] >> 1) & 0x03
+	hasCRC := data[1]&0x01 == 0
+	bitrateIndex := data[2] >> 4
+	sampleRateIndex := (data[2] >> 2) & 0x03
+	padding := int((data[2] >> 1) & 0x01)
+	mono := data[3]>>6 == 3
+
+	if versionBits == 1 || sampleRateIndex == 3 || bitrateIndex == 0 || bitrateIndex == 15 {
+		return frame, false
+	}
+	mpeg1 := versionBits == 3
+	layer := 4 - int(layerBits)
+
+	frame.sampleRate = mp3SampleRates[sampleRateIndex]
+	if versionBits == 2 {
+		frame.sampleRate /= 2
+	} else if versionBits == 0 {
+		frame.sampleRate /= 4
+	}
+	bitrate := mp3Bitrates[mpeg1][layer-1][bitrateIndex] * 1000
+
+	switch {
+	case layer == 1:
+		frame.samples = 384
+		frame.length = (12*bitrate/frame.sampleRate + padding) * 4
+	case layer == 2 || mpeg1:
+		frame.samples = 1152
+		frame.length = 144*bitrate/frame.sampleRate + padding
+	default:
+		frame.samples = 576
+		frame.length = 72*bitrate/frame.sampleRate + padding
+	}
+
+	frame.level = float64(bitrate)
+	if layer == 3 {
+		sideInfo := 4
+		if hasCRC {
+			sideInfo += 2
+		}
+		// 첫 번째 granule, 첫 번째 채널의 global_gain 위치 (bit)
+		gainBit := 30
+		if mpeg1 && mono {
+			gainBit = 39
+		} else if mpeg1 {
+			gainBit = 41
+		} else if !mono {
+			gainBit = 31
+		}
+		// global_gain은 1 증가할 때마다 1.5dB씩 커지는 로그 스케일이라 그대로 음량(dB)처럼 사용
+		if len(data) >= sideInfo+(gainBit+8)/8+1 {
+			frame.level = float64(readBits(data[sideInfo:], gainBit, 8))
+		}
+	}
+	return frame, true
+}
+
+// data의 bit 위치 offset부터 n bit를 읽음 (MSB 우선)
+func readBits(data []byte, offset, n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit := offset + i
+		v = v<<1 | int(data[bit/8]>>(7-uint(bit%8))&1)
+	}
+	return v
+}
+
+func analyzeMP3(data []byte) (int, []int, error) {
+	i := 0
+	// ID3v2 태그 건너뛰기, 크기는 7bit씩 쓰는 syncsafe 정수
+	if bytes.HasPrefix(data, []byte("ID3")) && len(data) >= 10 {
+		size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9])
+		i = 10 + size
+		if data[5]&0x10 != 0 {
+			i += 10
+		}
+	}
+
+	var totalSamples int64
+	sampleRate := 0
+	levels := []float64{}
+
+	for i+4 <= len(data) {
+		frame, ok := parseMP3Frame(data[i:])
+		if !ok || frame.length <= 0 {
+			// 프레임 사이의 쓰레기 값은 1byte씩 건너뛰면서 다음 sync를 찾음
+			i++
+			continue
+		}
+		end := i + frame.length
+		if end > len(data) {
+			end = len(data)
+		}
+
+		// 첫 프레임이 인코더 정보만 담긴 Xing/Info 프레임이면 재생되지 않으므로 제외
+		body := data[i:end]
+		if len(levels) > 0 || (!bytes.Contains(body, []byte("Xing")) && !bytes.Contains(body, []byte("Info"))) {
+			totalSamples += int64(frame.samples)
+			sampleRate = frame.sampleRate
+			levels = append(levels, frame.level)
+		}
+		i = end
+	}
+
+	if sampleRate == 0 {
+		return 0, nil, fmt.Errorf("no mp3 frame found")
+	}
+	durationMs := int(totalSamples * 1000 / int64(sampleRate))
+	return durationMs, downsampleWaveform(levels, true), nil
+}
+
+// ogg(vorbis/opus) 분석, 페이지의 granule position으로 길이를 계산하고
+// VBR 코덱은 소리가 클수록 데이터가 많아지므로 샘플당 byte 수로 음량을 추정
+func analyzeOgg(data []byte) (int, []int, error) {
+	var serial uint32
+	sampleRate := 0
+	preSkip := int64(0)
+	lastGranule := int64(0)
+	levels := []float64{}
+
+	i := 0
+	first := true
+	for i+27 <= len(data) && bytes.Equal(data[i:i+4], []byte("OggS")) {
+		granule := int64(binary.LittleEndian.Uint64(data[i+6 : i+14]))
+		pageSerial := binary.LittleEndian.Uint32(data[i+14 : i+18])
+		segments := int(data[i+26])
+		if i+27+segments > len(data) {
+			break
+		}
+		bodySize := 0
+		for _, lacing := range data[i+27 : i+27+segments] {
+			bodySize += int(lacing)
+		}
+		body := i + 27 + segments
+		end := body + bodySize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		if first {
+			serial = pageSerial
+			packet := data[body:end]
+			switch {
+			case bytes.HasPrefix(packet, []byte("\x01vorbis")) && len(packet) >= 16:
+				sampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+			case bytes.HasPrefix(packet, []byte("OpusHead")) && len(packet) >= 12:
+				// opus의 granule은 항상 48kHz 기준
+				sampleRate = 48000
+				preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+			default:
+				return 0, nil, fmt.Errorf("unsupported ogg codec")
+			}
+			first = false
+		} else if pageSerial == serial && granule > lastGranule {
+			// granule -1 은 페이지 안에서 끝나는 패킷이 없다는 뜻이므로 위 조건에서 제외됨
+			levels = append(levels, float64(end-body)/float64(granule-lastGranule))
+			lastGranule = granule
+		}
+		i = end
+	}
+
+	if sampleRate == 0 {
+		return 0, nil, fmt.Errorf("invalid ogg header")
+	}
+	samples := lastGranule - preSkip
+	if samples < 0 {
+		samples = 0
+	}
+	durationMs := int(samples * 1000 / int64(sampleRate))
+	return durationMs, downsampleWaveform(levels, true), nil
+}
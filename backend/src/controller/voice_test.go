@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// 16bit PCM wav, frames개의 샘플을 채널마다 같은 값으로 채움
+func testWAV(sampleRate, channels, frames int) []byte {
+	blockAlign := channels * 2
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+frames*blockAlign))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1))
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate*blockAlign))
+	binary.Write(&b, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&b, binary.LittleEndian, uint16(16))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(frames*blockAlign))
+	for f := 0; f < frames; f++ {
+		for ch := 0; ch < channels; ch++ {
+			binary.Write(&b, binary.LittleEndian, int16(f%1000*30))
+		}
+	}
+	return b.Bytes()
+}
+
+// MPEG1 layer III 128kbps 44.1kHz 프레임(417byte, 1152샘플)을 frames개 이어붙임
+func testMP3(frames int, id3 bool) []byte {
+	var b bytes.Buffer
+	if id3 {
+		b.Write([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20})
+		b.Write(make([]byte, 20))
+	}
+	for i := 0; i < frames; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		b.Write(frame)
+	}
+	return b.Bytes()
+}
+
+func testOggPage(serial uint32, granule int64, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("OggS")
+	b.Write([]byte{0, 0})
+	binary.Write(&b, binary.LittleEndian, granule)
+	binary.Write(&b, binary.LittleEndian, serial)
+	b.Write(make([]byte, 8))
+	b.WriteByte(1)
+	b.WriteByte(byte(len(body)))
+	b.Write(body)
+	return b.Bytes()
+}
+
+func testOpus(preSkip uint16, granules ...int64) []byte {
+	head := []byte("OpusHead\x01\x01")
+	head = append(head, byte(preSkip), byte(preSkip>>8))
+	head = append(head, make([]byte, 7)...)
+	data := testOggPage(7, 0, head)
+	for _, granule := range granules {
+		data = append(data, testOggPage(7, granule, make([]byte, 100))...)
+	}
+	return data
+}
+
+func testVorbis(sampleRate uint32, granules ...int64) []byte {
+	head := []byte("\x01vorbis\x00\x00\x00\x00\x01")
+	head = binary.LittleEndian.AppendUint32(head, sampleRate)
+	head = append(head, make([]byte, 14)...)
+	data := testOggPage(3, 0, head)
+	for _, granule := range granules {
+		data = append(data, testOggPage(3, granule, make([]byte, 50))...)
+	}
+	return data
+}
+
+func TestAnalyzeAudioDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		durationMs int
+		wantErr bool
+	}{
+		{"wav mono 8kHz", testWAV(8000, 1, 8000), 1000, false},
+		{"wav stereo 44.1kHz", testWAV(44100, 2, 22050), 500, false},
+		{"wav empty data", testWAV(16000, 1, 0), 0, false},
+		{"mp3 frame sync", testMP3(10, false), 261, false},
+		{"mp3 with id3", testMP3(100, true), 2612, false},
+		{"opus", testOpus(312, 24312, 48312), 1000, false},
+		{"vorbis", testVorbis(44100, 44100, 88200), 2000, false},
+		{"unknown", []byte("not audio at all"), 0, true},
+		{"ogg unknown codec", testOggPage(1, 0, []byte("Speex   ")), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			durationMs, waveform, err := analyzeAudio(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %dms", durationMs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if durationMs != tt.durationMs {
+				t.Errorf("durationMs = %d, want %d", durationMs, tt.durationMs)
+			}
+			if len(waveform) != waveformBars {
+				t.Fatalf("len(waveform) = %d, want %d", len(waveform), waveformBars)
+			}
+			for i, bar := range waveform {
+				if bar < 0 || bar > waveformMax {
+					t.Fatalf("waveform[%d] = %d, out of range", i, bar)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMP3Frame(t *testing.T) {
+	tests := []struct {
+		header []byte
+		ok bool
+		length int
+		samples int
+		sampleRate int
+	}{
+		// MPEG1 layer III 128kbps 44.1kHz
+		{[]byte{0xFF, 0xFB, 0x90, 0x00}, true, 417, 1152, 44100},
+		// padding bit
+		{[]byte{0xFF, 0xFB, 0x92, 0x00}, true, 418, 1152, 44100},
+		// MPEG2 layer III 64kbps 22.05kHz
+		{[]byte{0xFF, 0xF3, 0x80, 0x00}, true, 208, 576, 22050},
+		// 잘못된 bitrate / sample rate
+		{[]byte{0xFF, 0xFB, 0xF0, 0x00}, false, 0, 0, 0},
+		{[]byte{0xFF, 0xFB, 0x9C, 0x00}, false, 0, 0, 0},
+		{[]byte{0x00, 0x00, 0x00, 0x00}, false, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		frame, ok := parseMP3Frame(append(tt.header, make([]byte, 40)...))
+		if ok != tt.ok {
+			t.Errorf("parseMP3Frame(% x) ok = %v, want %v", tt.header, ok, tt.ok)
+			continue
+		}
+		if ok && (frame.length != tt.length || frame.samples != tt.samples || frame.sampleRate != tt.sampleRate) {
+			t.Errorf("parseMP3Frame(% x) = %d byte, %d samples, %dHz, want %d, %d, %d", tt.header, frame.length, frame.samples, frame.sampleRate, tt.length, tt.samples, tt.sampleRate)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/choigonyok/couple-chat-service/src/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 단어 랭킹 기본 기간과 주간 비교 기간 (오늘 포함 7일)
+	defaultRankDays = 7
+	monthRankDays = 30
+	maxRankNum = 100
+	// 제외 단어 한 번에 추가할 수 있는 최대 개수
+	maxExceptWordImport = 500
+)
+
+// 단어의 이번 주와 지난 주 사용 횟수
+type wordTrend struct {
+	Word string `json:"word"`
+	This_week int `json:"this_week"`
+	Last_week int `json:"last_week"`
+	Delta int `json:"delta"`
+}
+
+// 집계할 단어 최대 길이, 띄어쓰기 없이 길게 친 문장은 단어로 보지 않음
+const maxWordLength = 50
+
+// 채팅에 나온 단어별 횟수, 단어는 설정된 토크나이저로 나눔
+func countWords(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range getWordTokenizer().Tokenize(text) {
+		if utf8.RuneCountInString(word) > maxWordLength {
+			continue
+		}
+		counts[word]++
+	}
+	return counts
+}
+
+// 채팅 작성일(YYYY-MM-DD), 형식이 다른 예전 데이터는 오늘로 집계
+func chatDate(write_time string) string {
+	if t, err := time.Parse(chatTimeLayout, write_time); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return getTimeNow().Format("2006-01-02")
+}
+
+// 텍스트 채팅이 저장/삭제될 때 작성자의 그날 단어 횟수를 더하거나(sign 1) 뺌(sign -1)
+func updateWordCounts(conn_id int, chatData model.ChatData, sign int) {
+	if chatData.Is_file == 1 {
+		return
+	}
+	counts := countWords(chatData.Text_body)
+	for word := range counts {
+		counts[word] *= sign
+	}
+
+	err := model.AddWordCounts(conn_id, chatData.Writer_id, chatDate(chatData.Write_time), counts)
+	if err != nil {
+		fmt.Println("ERROR #266 : ", err.Error())
+	}
+}
+
+// 랭킹 기간(?window=)의 from, to(YYYY-MM-DD, 둘 다 포함), 빈 값이면 기간 제한 없음
+// day: 오늘, week: 오늘 포함 7일(기본값), month: 오늘 포함 30일, all: 전체, custom: from~to
+func getRankWindow(c *gin.Context) (string, string, bool) {
+	today := getTimeNow()
+	switch c.DefaultQuery("window", "week") {
+	case "day":
+		return today.Format("2006-01-02"), today.Format("2006-01-02"), true
+	case "week":
+		return today.AddDate(0, 0, -(defaultRankDays - 1)).Format("2006-01-02"), today.Format("2006-01-02"), true
+	case "month":
+		return today.AddDate(0, 0, -(monthRankDays - 1)).Format("2006-01-02"), today.Format("2006-01-02"), true
+	case "all":
+		return "", "", true
+	case "custom":
+		from, to := c.Query("from"), c.Query("to")
+		if !dateRegexp.MatchString(from) || !dateRegexp.MatchString(to) || from > to {
+			return "", "", false
+		}
+		return from, to, true
+	}
+	return "", "", false
+}
+
+// 단어 목록만 필요한 예전 클라이언트용
+func wordsOf(wordCountDatas []model.WordCountData) []string {
+	words := []string{}
+	for _, wordCountData := range wordCountDatas {
+		words = append(words, wordCountData.Word)
+	}
+	return words
+}
+
+// 랭킹에 오른 단어들의 이번 주(오늘 포함 7일)와 지난 주 사용 횟수 비교
+func getWordTrends(conn_id int, viewer_uuid, uuid string, wordCountDatas []model.WordCountData) ([]wordTrend, error) {
+	today := getTimeNow()
+	words := wordsOf(wordCountDatas)
+	thisWeek, err := model.GetWordCountsByWords(conn_id, viewer_uuid, uuid, today.AddDate(0, 0, -(defaultRankDays-1)).Format("2006-01-02"), today.Format("2006-01-02"), words)
+	if err != nil {
+		return nil, err
+	}
+	lastWeek, err := model.GetWordCountsByWords(conn_id, viewer_uuid, uuid, today.AddDate(0, 0, -(2*defaultRankDays-1)).Format("2006-01-02"), today.AddDate(0, 0, -defaultRankDays).Format("2006-01-02"), words)
+	if err != nil {
+		return nil, err
+	}
+
+	trends := []wordTrend{}
+	for _, word := range words {
+		trends = append(trends, wordTrend{word, thisWeek[word], lastWeek[word], thisWeek[word] - lastWeek[word]})
+	}
+	return trends, nil
+}
+
+// 제외 단어를 랭킹 단어와 같은 형태로 맞춤, 잘못된 단어면 에러 메시지 리턴
+// 패턴(*)이 없는 단어는 토크나이저를 거쳐서 "사랑해"를 제외하면 랭킹의 "사랑"이 제외됨
+func normalizeExceptWord(except_word string) (string, string) {
+	word := strings.ToLower(strings.TrimSpace(except_word))
+	if strings.Trim(word, "*") == "" {
+		return "", "EMPTY_WORD"
+	}
+	if utf8.RuneCountInString(word) > maxWordLength {
+		return "", "TOO_LONG_WORD"
+	}
+	// LIKE 패턴으로 비교하므로 %, _ 같은 글자는 받지 않음
+	if strings.ContainsAny(word, "%_\\\"' \t\n/") {
+		return "", "INVALID_WORD"
+	}
+	if !strings.Contains(word, "*") {
+		if tokens := getWordTokenizer().Tokenize(word); len(tokens) == 1 {
+			word = tokens[0]
+		}
+	}
+	return word, ""
+}
+
+// 제외 단어를 저장할 uuid, 커플 목록이면 빈 값
+func exceptWordScope(uuid, scope string) (string, bool) {
+	switch scope {
+	case "", "couple":
+		return "", true
+	case "me":
+		return uuid, true
+	}
+	return "", false
+}
+
+// 저장된 채팅으로 모든 커플의 단어 횟수를 다시 계산 (관리자, 집계 테이블 도입 전 채팅 반영용)
+func RebuildWordCountsHandler(c *gin.Context) {
+	if !checkAdmin(c) {
+		return
+	}
+
+	connIDs, usrs, err := model.GetAllConnections()
+	if err != nil {
+		fmt.Println("ERROR #267 : ", err.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	chatCount := 0
+	for i, conn_id := range connIDs {
+		chatDatas, err := model.GetTextChatsByWriterIDs(usrs[i][:])
+		if err != nil {
+			fmt.Println("ERROR #268 : ", err.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// 작성자, 날짜별로 모아서 한 번에 저장
+		buckets := make(map[[2]string]map[string]int)
+		for _, chatData := range chatDatas {
+			key := [2]string{chatData.Writer_id, chatDate(chatData.Write_time)}
+			if buckets[key] == nil {
+				buckets[key] = make(map[string]int)
+			}
+			for word, count := range countWords(chatData.Text_body) {
+				buckets[key][word] += count
+			}
+		}
+
+		err = model.DeleteWordCountsByConnID(conn_id)
+		if err == nil {
+			for key, counts := range buckets {
+				err = model.AddWordCounts(conn_id, key[0], key[1], counts)
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			fmt.Println("ERROR #269 : ", err.Error())
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		chatCount += len(chatDatas)
+	}
+
+	sendData := struct {
+		Connections int `json:"connections"`
+		Chats int `json:"chats"`
+	}{len(connIDs), chatCount}
+
+	marshaledData, err2 := json.Marshal(sendData)
+	if err2 != nil {
+		fmt.Println("ERROR #270 : ", err2.Error())
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c.Writer.Write(marshaledData)
+}
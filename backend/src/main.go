@@ -17,7 +17,7 @@ func originConfig() cors.Config{
 	// localhost:3000로 origin allow 하면 통신 안됨
 
 	config.AllowMethods= []string{"GET", "POST", "DELETE", "PUT"}
-	config.AllowHeaders = []string{"Content-type"}
+	config.AllowHeaders = []string{"Content-type", "X-Admin-Token"}
 	config.AllowCredentials = true
 	return config
 }
@@ -33,6 +33,11 @@ func main() {
 	
 	controller.ConnectDB("mysql", os.Getenv("DB_USER")+":"+os.Getenv("DB_PASSWORD")+`@tcp(`+os.Getenv("DB_HOST")+`)/`+os.Getenv("DB_NAME"))	// DB 초기 연결
 	defer controller.UnConnectDB()
+
+	controller.BackfillPhotoAttachments()	// 갤러리 이전에 보낸 사진을 갤러리에 추가
+	controller.StartUploadCleaner()	// 방치된 분할 업로드 정리
+	controller.StartDailyQuestionScheduler()	// 오늘의 질문 보내기
+	controller.StartReminderScheduler()	// 일정 알림 보내기
 	
 	e.POST("/api/usr", controller.SignUpHandler)								// 회원가입
 	e.DELETE("/api/usr", controller.WithDrawalHandler)							// 회원탈퇴
@@ -54,20 +59,72 @@ func main() {
 	e.DELETE("/api/request/:param", controller.DeleteOneRequestHandler)			// 받은 요청 중 선택해서 요청을 삭제
 
 	e.GET("/api/answer", controller.GetAnswerHandler)							// 그동안 답한 내용들을 모아서 보여주기 위한 API
-
-	e.POST("/api/file", controller.InsertFileHandler)							// 채팅으로 보낸 파일 서버에 저장
-	e.GET("/api/file/:chatID", controller.GetFileHandler)						// chatpage 렌더링용 썸네일 이미지 불러오기
+	e.GET("/api/answer/archive", controller.GetAnswerArchiveHandler)			// 두 사람이 모두 답한 답변 모아보기 (?page=&limit=&category=&from=&to=&format=csv)
+	e.GET("/api/answer/insights", controller.GetAnswerInsightsHandler)			// 객관식 질문에서 같은 답을 고른 비율
+	e.GET("/api/answer/:questionID", controller.GetMyAnswerHandler)				// 받은 질문에 대한 내 답변과 수정 가능 여부
+	e.PUT("/api/answer/:questionID", controller.UpdateAnswerHandler)			// 답변 작성/수정 (상대가 답하기 전까지)
+	e.GET("/api/question/pending", controller.GetPendingQuestionsHandler)		// 내가 아직 답하지 않은 질문 목록 (?include_deferred=1)
+	e.POST("/api/question/pending/:questionID/skip", controller.SkipPendingQuestionHandler)		// 대기 중인 질문 건너뛰기
+	e.POST("/api/question/pending/:questionID/defer", controller.DeferPendingQuestionHandler)	// 대기 중인 질문 미루기 (?minutes=)
+	e.GET("/api/question/daily", controller.GetDailyQuestionHandler)			// 오늘의 질문 설정 불러오기
+	e.PUT("/api/question/daily", controller.UpdateDailyQuestionHandler)			// 오늘의 질문 켜기/끄기, 보낼 시간 설정
+
+	e.POST("/api/file", controller.InsertFileHandler)							// 채팅으로 보낸 파일 서버에 저장 (voice=1이면 음성 메시지)
+	e.GET("/api/file/:chatID", controller.GetFileHandler)						// chatpage 렌더링용 썸네일 이미지 불러오기 (?size=160|320|640, 없으면 원본)
 	e.GET("/api/file/name/:chatID", controller.GetFileNameHandler)				// 파일이름+확장자 찾기
+	e.GET("/api/file/usage", controller.GetStorageUsageHandler)				// 커플의 파일 저장공간 사용량 불러오기
+
+	e.POST("/api/upload", controller.InitiateUploadHandler)					// 분할 업로드 시작
+	e.GET("/api/upload/:uploadID", controller.GetUploadHandler)					// 분할 업로드 진행상황(offset) 불러오기
+	e.PUT("/api/upload/:uploadID", controller.UploadPartHandler)				// 분할 업로드 조각 전송 (?offset=)
+	e.POST("/api/upload/:uploadID", controller.CompleteUploadHandler)			// 분할 업로드 완료, 조각 합치기 및 checksum 검증
+	e.DELETE("/api/upload/:uploadID", controller.AbortUploadHandler)			// 분할 업로드 취소
+
+	e.GET("/api/gallery", controller.GetGalleryHandler)							// 채팅으로 주고받은 사진 모아보기 (?page=&limit=&month=&favorite=)
+	e.GET("/api/gallery/month", controller.GetGalleryMonthsHandler)				// 달별 사진 개수 불러오기
+	e.POST("/api/gallery/favorite/:chatID", controller.InsertFavoriteHandler)	// 사진 즐겨찾기 추가
+	e.DELETE("/api/gallery/favorite/:chatID", controller.DeleteFavoriteHandler)	// 사진 즐겨찾기 취소
+
+	e.POST("/api/album", controller.InsertAlbumHandler)							// 앨범 만들기
+	e.GET("/api/album", controller.GetAlbumsHandler)							// 앨범 목록 불러오기
+	e.PUT("/api/album/:albumID", controller.UpdateAlbumHandler)					// 앨범 이름 변경
+	e.DELETE("/api/album/:albumID", controller.DeleteAlbumHandler)				// 앨범 삭제
+	e.GET("/api/album/:albumID", controller.GetAlbumPhotosHandler)				// 앨범에 담긴 사진 불러오기
+	e.POST("/api/album/:albumID/:chatID", controller.InsertAlbumPhotoHandler)	// 앨범에 사진 담기
+	e.DELETE("/api/album/:albumID/:chatID", controller.DeleteAlbumPhotoHandler)	// 앨범에서 사진 빼기
 
 	e.GET("/api/chat/word/:param", controller.GetChatWordHandler)				// 단어 기반 채팅 검색
+	e.GET("/api/chat/search", controller.SearchChatHandler)						// 관련도 순 채팅 검색 (snippet, 보낸사람/기간/종류 필터, 페이지)
+	e.GET("/api/chat/history/:chatID", controller.GetChatHistoryHandler)		// chatID 기준 앞뒤 채팅 기록 불러오기 (검색 결과 위치로 이동)
 	e.GET("/api/chat/date", controller.GetChatDateHandler)						// 날짜 기반 채팅 검색
+	e.GET("/api/chat/range", controller.GetChatRangeHandler)					// 기간 내 채팅 불러오기 (?from=&to=&tz=&page=&limit=)
+	e.GET("/api/chat/calendar", controller.GetChatCalendarHandler)				// 기간 내 날짜별 채팅 개수 (달력 히트맵)
+	e.GET("/api/stats", controller.GetRelationshipStatsHandler)					// 커플 채팅 통계 대시보드 (?from=&to=&tz=)
+	e.GET("/api/mood", controller.GetMoodTrendHandler)							// 날짜별 두 사람의 감정 점수 추이 (?from=&to=&tz=)
+	e.GET("/api/mood/setting", controller.GetMoodSettingHandler)				// 감정 분석 설정 불러오기
+	e.PUT("/api/mood/setting", controller.UpdateMoodSettingHandler)				// 감정 분석 켜기/끄기
 
 	e.POST("/api/anniversary", controller.InsertAnniversaryHandler)				// 일정, 기념일 추가
-	e.GET("/api/anniversary", controller.GetAnniversaryHandler)					// 일정, 기념일 불러오기
+	e.GET("/api/anniversary", controller.GetAnniversaryHandler)					// 일정, 기념일 불러오기 (?year=&month= 또는 ?from=&to=, 반복/음력 일정과 자동 기념일 포함)
 	e.GET("/api/anniversary/dday", controller.GetDDayHandler)					// D-DAY 불러오기
 	e.DELETE("/api/anniversary/:id", controller.DeleteAnniversaryHandler)		// 일정, 기념일 삭제
-
-	e.GET("/api/rank/:ranknum", controller.GetMostUsedWordsHandler)				// 사용자가 가장 많이 사용한 단어 랭킹 보여주기
+	e.PUT("/api/anniversary/:id/reminder", controller.UpdateAnniversaryReminderHandler)	// 일정 알림 시간 변경 (일정 시작 몇 분 전, 채팅으로 알림)
+	e.GET("/api/calendar/export", controller.ExportCalendarHandler)				// 일정, 기념일 .ics 파일로 내보내기
+	e.POST("/api/calendar/import", controller.ImportCalendarHandler)			// .ics 파일의 일정 한 번에 추가 (?tz=)
+	e.GET("/api/calendar/feed", controller.GetCalendarFeedHandler)				// 캘린더 구독 주소 불러오기
+	e.POST("/api/calendar/feed", controller.CreateCalendarFeedHandler)			// 캘린더 구독 주소 만들기 (다시 만들면 예전 주소는 사용 불가)
+	e.DELETE("/api/calendar/feed", controller.DeleteCalendarFeedHandler)		// 캘린더 구독 주소 삭제
+	e.GET("/api/calendar/feed/:token", controller.CalendarFeedHandler)			// 캘린더 앱 구독용 iCalendar (로그인 없이 토큰으로 접근)
+
+	e.GET("/api/admin/question", controller.GetQuestionsHandler)				// 질문 목록 불러오기 (관리자, ?category=&language=&active=)
+	e.POST("/api/admin/question", controller.InsertQuestionHandler)				// 질문 추가 (관리자)
+	e.POST("/api/admin/question/import", controller.ImportQuestionsHandler)		// 질문 여러 개 가져오기, CSV/JSON (관리자)
+	e.PUT("/api/admin/question/:questionID", controller.UpdateQuestionHandler)	// 질문 수정 (관리자)
+	e.DELETE("/api/admin/question/:questionID", controller.DeactivateQuestionHandler)	// 질문 비활성화 (관리자)
+	e.POST("/api/admin/answer/repair", controller.RepairAnswersHandler)			// 중복 답변 레코드 합치기 (관리자, ?dry_run=1)
+	e.POST("/api/admin/wordcount/rebuild", controller.RebuildWordCountsHandler)	// 저장된 채팅으로 단어 횟수 다시 계산 (관리자)
+
+	e.GET("/api/rank/:ranknum", controller.GetMostUsedWordsHandler)				// 사용자가 가장 많이 사용한 단어 랭킹 보여주기 (?window=day|week|month|all|custom)
 
 	e.GET("/ws", controller.UpgradeHandler)										// Websocket 프로토콜로 업그레이드 및 메시지 read/write
 
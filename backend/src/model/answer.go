@@ -0,0 +1,289 @@
+package model
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// 답변 모아보기 조건, From/To는 YYYY-MM-DD (To 포함), Limit이 0이면 전체
+type AnswerFilter struct {
+	Category string
+	From string
+	To string
+	Limit int
+	Offset int
+}
+
+// 객관식 질문에서 두 사람이 같은 보기를 고른 비율, 카테고리별
+type AnswerInsightData struct {
+	Category string `json:"category"`
+	Answered_count int `json:"answered_count"`
+	Choice_count int `json:"choice_count"`
+	Matching_count int `json:"matching_count"`
+}
+
+// 답변 복구 결과
+type AnswerRepairResult struct {
+	Duplicate_rows_removed int `json:"duplicate_rows_removed"`
+	Unique_key_added bool `json:"unique_key_added"`
+}
+
+// 커플의 질문에 대한 내 답변 저장
+// 상대가 답하기 전까지는 몇 번이든 수정할 수 있고, 상대가 답한 뒤에는 내가 처음 답하는 경우만 저장됨
+// 저장 여부와 이번 답변으로 두 사람 답변이 모두 채워졌는지, 저장 후의 답변을 리턴
+func SaveAnswer(connection_id, question_id int, is_first bool, answer string) (AnswerData, bool, bool, error) {
+	var answerData AnswerData
+
+	tx, err := db.Begin()
+	if err != nil {
+		return answerData, false, false, err
+	}
+	defer tx.Rollback()
+
+	r, err := tx.Query(`SELECT a.answer_id, a.first_answer, a.second_answer, a.answer_date, q.question_contents FROM answer a JOIN question q ON a.question_id = q.question_id WHERE a.connection_id = ? and a.question_id = ? FOR UPDATE`, connection_id, question_id)
+	if err != nil {
+		return answerData, false, false, err
+	}
+	isExist := r.Next()
+	if isExist {
+		err = r.Scan(&answerData.Answer_id, &answerData.FirstAnswer, &answerData.SecondAnswer, &answerData.AnswerDate, &answerData.QuestionContents)
+	}
+	r.Close()
+	if err != nil || !isExist {
+		return answerData, false, false, err
+	}
+	answerData.Connection_id = connection_id
+	answerData.Question_id = question_id
+
+	myAnswer, partnerAnswer := &answerData.SecondAnswer, answerData.FirstAnswer
+	column := "second_answer"
+	if is_first {
+		myAnswer, partnerAnswer = &answerData.FirstAnswer, answerData.SecondAnswer
+		column = "first_answer"
+	}
+	if partnerAnswer != "not-written" && *myAnswer != "not-written" {
+		return answerData, false, false, nil
+	}
+
+	_, err = tx.Exec(`UPDATE answer SET `+column+` = ? WHERE answer_id = ?`, answer, answerData.Answer_id)
+	if err != nil {
+		return answerData, false, false, err
+	}
+	*myAnswer = answer
+	return answerData, true, partnerAnswer != "not-written", tx.Commit()
+}
+
+func GetAnswerByConnIDandQuestionID(connection_id, question_id int) (AnswerData, bool, error) {
+	var answerData AnswerData
+	var answerOptions sql.NullString
+
+	r, err := db.Query(`SELECT a.answer_id, a.first_answer, a.second_answer, a.answer_date, q.question_contents, q.category, q.answer_options FROM answer a JOIN question q ON a.question_id = q.question_id WHERE a.connection_id = ? and a.question_id = ?`, connection_id, question_id)
+	if err != nil {
+		return answerData, false, err
+	}
+	defer r.Close()
+
+	if !r.Next() {
+		return answerData, false, nil
+	}
+	err = r.Scan(&answerData.Answer_id, &answerData.FirstAnswer, &answerData.SecondAnswer, &answerData.AnswerDate, &answerData.QuestionContents, &answerData.Category, &answerOptions)
+	answerData.Connection_id = connection_id
+	answerData.Question_id = question_id
+	answerData.Answer_options = parseAnswerOptions(answerOptions)
+	return answerData, err == nil, err
+}
+
+// 두 사람이 모두 답한 답변만 조회하는 조건
+func answerFilterCondition(connection_id int, filter AnswerFilter) (string, []interface{}) {
+	condition := `WHERE a.connection_id = ? and a.first_answer != "not-written" and a.second_answer != "not-written"`
+	args := []interface{}{connection_id}
+	if filter.Category != "" {
+		condition += ` and q.category = ?`
+		args = append(args, filter.Category)
+	}
+	if filter.From != "" {
+		condition += ` and a.answer_date >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		condition += ` and a.answer_date < DATE_FORMAT(DATE_ADD(?, INTERVAL 1 DAY), "%Y-%m-%d")`
+		args = append(args, filter.To)
+	}
+	return condition, args
+}
+
+// 두 사람이 모두 답한 답변을 최신순으로 불러오고, 전체 개수도 같이 리턴
+func GetAnswerArchive(connection_id int, filter AnswerFilter) ([]AnswerData, int, error) {
+	condition, args := answerFilterCondition(connection_id, filter)
+
+	r1, err := db.Query(`SELECT COUNT(*) FROM answer a JOIN question q ON a.question_id = q.question_id `+condition, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := 0
+	if r1.Next() {
+		err = r1.Scan(&total)
+	}
+	r1.Close()
+	if err != nil || total == 0 {
+		return nil, total, err
+	}
+
+	query := `SELECT a.answer_id, a.question_id, q.question_contents, q.category, q.answer_options, a.first_answer, a.second_answer, a.answer_date FROM answer a JOIN question q ON a.question_id = q.question_id ` + condition + ` ORDER BY a.answer_id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit) + ` OFFSET ` + strconv.Itoa(filter.Offset)
+	}
+	r2, err := db.Query(query, args...)
+	if err != nil {
+		return nil, total, err
+	}
+	defer r2.Close()
+
+	answerDatas := []AnswerData{}
+	var answerData AnswerData
+	var answerOptions sql.NullString
+	for r2.Next() {
+		err = r2.Scan(&answerData.Answer_id, &answerData.Question_id, &answerData.QuestionContents, &answerData.Category, &answerOptions, &answerData.FirstAnswer, &answerData.SecondAnswer, &answerData.AnswerDate)
+		if err != nil {
+			return nil, total, err
+		}
+		answerData.Connection_id = connection_id
+		answerData.Answer_options = parseAnswerOptions(answerOptions)
+		answerDatas = append(answerDatas, answerData)
+	}
+	return answerDatas, total, nil
+}
+
+// 두 사람이 모두 답한 질문 수와 그중 객관식 질문에서 같은 보기를 고른 수를 카테고리별로 집계
+func GetAnswerInsights(connection_id int, filter AnswerFilter) ([]AnswerInsightData, error) {
+	condition, args := answerFilterCondition(connection_id, filter)
+
+	r, err := db.Query(`SELECT q.category, COUNT(*), IFNULL(SUM(q.answer_options IS NOT NULL), 0), IFNULL(SUM(q.answer_options IS NOT NULL and a.first_answer = a.second_answer), 0) FROM answer a JOIN question q ON a.question_id = q.question_id `+condition+` GROUP BY q.category ORDER BY q.category ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	insightDatas := []AnswerInsightData{}
+	var insightData AnswerInsightData
+	for r.Next() {
+		err = r.Scan(&insightData.Category, &insightData.Answered_count, &insightData.Choice_count, &insightData.Matching_count)
+		if err != nil {
+			return nil, err
+		}
+		insightDatas = append(insightDatas, insightData)
+	}
+	return insightDatas, nil
+}
+
+type answerRow struct {
+	answer_id int
+	connection_id int
+	question_id int
+	first_answer string
+	second_answer string
+}
+
+// 답변이 question_id로만 업데이트되던 때 생긴 중복 answer 레코드 정리
+// 1. 같은 (connection_id, question_id) 레코드가 여러 개면 가장 먼저 생긴 레코드에 작성된 답변을 합치고 나머지 삭제
+// 2. (connection_id, question_id) unique key가 없으면 추가
+// "응", "좋아"나 객관식 답변은 원래 여러 커플이 똑같이 답하므로 커플 사이에 같은 답변이 있어도 덮어써진 것으로 보지 않음
+// dryRun이면 변경하지 않고 바뀔 개수만 리턴
+func RepairAnswers(dryRun bool) (AnswerRepairResult, error) {
+	var result AnswerRepairResult
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	r, err := tx.Query(`SELECT answer_id, connection_id, IFNULL(question_id, 0), IFNULL(first_answer, "not-written"), IFNULL(second_answer, "not-written") FROM answer ORDER BY answer_id ASC FOR UPDATE`)
+	if err != nil {
+		return result, err
+	}
+	rows := []answerRow{}
+	var row answerRow
+	for r.Next() {
+		err = r.Scan(&row.answer_id, &row.connection_id, &row.question_id, &row.first_answer, &row.second_answer)
+		if err != nil {
+			r.Close()
+			return result, err
+		}
+		rows = append(rows, row)
+	}
+	r.Close()
+
+	// 1. 중복 레코드 합치기
+	type answerKey struct {
+		connection_id int
+		question_id int
+	}
+	kept := make(map[answerKey]int)
+	merged := []answerRow{}
+	originals := []answerRow{}
+	removedIDs := []int{}
+	for _, row := range rows {
+		key := answerKey{row.connection_id, row.question_id}
+		i, ok := kept[key]
+		if !ok {
+			kept[key] = len(merged)
+			merged = append(merged, row)
+			originals = append(originals, row)
+			continue
+		}
+		if merged[i].first_answer == "not-written" {
+			merged[i].first_answer = row.first_answer
+		}
+		if merged[i].second_answer == "not-written" {
+			merged[i].second_answer = row.second_answer
+		}
+		removedIDs = append(removedIDs, row.answer_id)
+	}
+	result.Duplicate_rows_removed = len(removedIDs)
+
+	for i, row := range merged {
+		if dryRun || merged[i] == originals[i] {
+			continue
+		}
+		_, err = tx.Exec(`UPDATE answer SET first_answer = ?, second_answer = ? WHERE answer_id = ?`, merged[i].first_answer, merged[i].second_answer, row.answer_id)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	r2, err := tx.Query(`SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() and table_name = "answer" and index_name = "connection_question"`)
+	if err != nil {
+		return result, err
+	}
+	keyCount := 0
+	if r2.Next() {
+		err = r2.Scan(&keyCount)
+	}
+	r2.Close()
+	if err != nil {
+		return result, err
+	}
+	result.Unique_key_added = keyCount == 0
+
+	if dryRun {
+		return result, nil
+	}
+
+	for _, answer_id := range removedIDs {
+		_, err = tx.Exec(`DELETE FROM answer WHERE answer_id = ?`, answer_id)
+		if err != nil {
+			return result, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return result, err
+	}
+
+	// ALTER TABLE은 실행 시 트랜잭션을 바로 커밋시키므로 중복 정리를 커밋한 뒤 따로 실행
+	if result.Unique_key_added {
+		_, err = db.Exec(`ALTER TABLE answer ADD UNIQUE KEY connection_question (connection_id, question_id)`)
+	}
+	return result, err
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type AttachmentData struct {
+	Attachment_id int `json:"attachment_id"`
+	Chat_id int `json:"chat_id"`
+	Connection_id int `json:"connection_id"`
+	File_name string `json:"file_name"`
+	Mime_type string `json:"mime_type"`
+	File_size int64 `json:"file_size"`
+}
+
+func InsertAttachment(data AttachmentData) error {
+	_, err := db.Exec(`INSERT INTO attachment (chat_id, connection_id, file_name, mime_type, file_size) VALUES (?, ?, ?, ?, ?)`, data.Chat_id, data.Connection_id, data.File_name, data.Mime_type, data.File_size)
+	return err
+}
+
+// 저장공간 제한(quota)을 넘지 않을 때만 파일 채팅과 첨부파일 레코드를 저장하고 chat_id 리턴, 제한을 넘으면 false
+// 동시에 올린 파일들이 같이 제한을 넘지 않도록 커넥션 행을 잠근 채로 사용량 확인과 저장을 한 트랜잭션에서 함
+func InsertFileChatWithinQuota(writer_id, write_time string, is_image int, data AttachmentData, quota int64) (int, bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	r, err := tx.Query(`SELECT connection_id FROM connection WHERE connection_id = ? FOR UPDATE`, data.Connection_id)
+	if err != nil {
+		return 0, false, err
+	}
+	r.Close()
+
+	r, err = tx.Query(`SELECT COALESCE(SUM(file_size), 0) FROM attachment WHERE connection_id = ?`, data.Connection_id)
+	if err != nil {
+		return 0, false, err
+	}
+	var usedBytes int64
+	if r.Next() {
+		err = r.Scan(&usedBytes)
+	}
+	r.Close()
+	if err != nil {
+		return 0, false, err
+	}
+	if usedBytes+data.File_size > quota {
+		return 0, false, nil
+	}
+
+	result, err := tx.Exec(`INSERT INTO chat (text_body, writer_id, write_time, is_file, is_image) VALUES (?, ?, ?, 1, ?)`, data.File_name, writer_id, write_time, is_image)
+	if err != nil {
+		return 0, false, err
+	}
+	chat_id, err := result.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	_, err = tx.Exec(`INSERT INTO attachment (chat_id, connection_id, file_name, mime_type, file_size) VALUES (?, ?, ?, ?, ?)`, chat_id, data.Connection_id, data.File_name, data.Mime_type, data.File_size)
+	if err != nil {
+		return 0, false, err
+	}
+	return int(chat_id), true, tx.Commit()
+}
+
+func GetAttachmentByChatID(chat_id int) (AttachmentData, error) {
+	var attachmentData AttachmentData
+
+	r, err := db.Query(`SELECT attachment_id, chat_id, connection_id, file_name, mime_type, file_size FROM attachment WHERE chat_id = `+strconv.Itoa(chat_id))
+	if err != nil {
+		return attachmentData, err
+	}
+	defer r.Close()
+
+	if r.Next() {
+		err = r.Scan(&attachmentData.Attachment_id, &attachmentData.Chat_id, &attachmentData.Connection_id, &attachmentData.File_name, &attachmentData.Mime_type, &attachmentData.File_size)
+	}
+	return attachmentData, err
+}
+
+// 커플이 사용중인 저장공간(byte)과 파일 개수
+func GetStorageUsageByConnID(connection_id int) (int64, int, error) {
+	r, err := db.Query(`SELECT COALESCE(SUM(file_size), 0), COUNT(*) FROM attachment WHERE connection_id = `+strconv.Itoa(connection_id))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer r.Close()
+
+	var usedBytes int64
+	var fileCount int
+	if r.Next() {
+		err = r.Scan(&usedBytes, &fileCount)
+	}
+	return usedBytes, fileCount, err
+}
+
+func DeleteAttachmentByChatID(chat_id int) error {
+	_, err := db.Exec("DELETE FROM attachment WHERE chat_id = "+strconv.Itoa(chat_id))
+	return err
+}
+
+// 음성 메시지로 표시하도록 chat에 표시하고, 재생 길이와 파형을 첨부파일에 저장
+func UpdateVoiceByChatID(chat_id, duration_ms int, waveform []int) error {
+	marshaledWaveform, err := json.Marshal(waveform)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE chat SET is_voice = 1 WHERE chat_id = "+strconv.Itoa(chat_id))
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE attachment SET duration_ms = ?, waveform = ? WHERE chat_id = ?`, duration_ms, string(marshaledWaveform), chat_id)
+	return err
+}
+
+// 음성 메시지 여부와 재생 길이, 파형 불러오기
+func GetVoiceByChatID(chat_id int) (ChatData, error) {
+	var chatData ChatData
+
+	r, err := db.Query(`SELECT c.is_voice, COALESCE(a.duration_ms, 0), COALESCE(a.waveform, '') FROM chat c LEFT JOIN attachment a ON a.chat_id = c.chat_id WHERE c.chat_id = `+strconv.Itoa(chat_id))
+	if err != nil {
+		return chatData, err
+	}
+	defer r.Close()
+
+	var waveform string
+	if r.Next() {
+		err = r.Scan(&chatData.Is_voice, &chatData.Duration_ms, &waveform)
+		chatData.Waveform = parseWaveform(waveform)
+	}
+	return chatData, err
+}
+
+// DB에 JSON 배열 문자열로 저장된 파형을 변환, 음성 메시지가 아니면 nil
+func parseWaveform(waveform string) []int {
+	if waveform == "" {
+		return nil
+	}
+	var parsed []int
+	err := json.Unmarshal([]byte(waveform), &parsed)
+	if err != nil {
+		return nil
+	}
+	return parsed
+}
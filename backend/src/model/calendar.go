@@ -0,0 +1,70 @@
+package model
+
+// 커플 캘린더 구독 주소의 토큰, 만든 적 없으면 ""
+func GetCalendarFeedToken(connection_id int) (string, error) {
+	r, err := db.Query(`SELECT token FROM calendar_feed WHERE connection_id = ?`, connection_id)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var token string
+	if r.Next() {
+		err = r.Scan(&token)
+	}
+	return token, err
+}
+
+// 구독 토큰 저장, 이미 있으면 새 토큰으로 바꿔서 예전 주소는 더 이상 쓸 수 없음
+func UpsertCalendarFeedToken(connection_id int, token string) error {
+	_, err := db.Exec(`INSERT INTO calendar_feed (connection_id, token) VALUES (?, ?) ON DUPLICATE KEY UPDATE token = VALUES(token)`, connection_id, token)
+	return err
+}
+
+func DeleteCalendarFeedToken(connection_id int) error {
+	_, err := db.Exec(`DELETE FROM calendar_feed WHERE connection_id = ?`, connection_id)
+	return err
+}
+
+// 구독 토큰의 커플, 없는 토큰이면 0
+func GetConnIDByCalendarFeedToken(token string) (int, error) {
+	r, err := db.Query(`SELECT connection_id FROM calendar_feed WHERE token = ?`, token)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var connection_id int
+	if r.Next() {
+		err = r.Scan(&connection_id)
+	}
+	return connection_id, err
+}
+
+// 커플의 저장된 일정 전부
+func GetAnniversariesByConnID(connection_id int) ([]AnniversaryData, error) {
+	r, err := db.Query(selectAnniversary+`WHERE connection_id = ? ORDER BY start_time ASC, anniversary_id ASC`, connection_id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return scanAnniversaries(r)
+}
+
+// 일정 여러 개를 한 번에 저장, 하나라도 실패하면 아무것도 저장하지 않음
+func InsertAnniversaries(datas []AnniversaryData) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, data := range datas {
+		_, err := tx.Exec(`INSERT INTO anniversary (connection_id, year, month, date, contents, d_day, recurrence, interval_days, is_lunar, is_leap_month, start_time, end_time, is_all_day, timezone, location, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, data.Connection_id, data.Year, data.Month, data.Date, data.Contents, data.D_day, data.Recurrence, data.Interval_days, data.Is_lunar, data.Is_leap_month, data.Start_time, data.End_time, data.Is_all_day, data.Timezone, data.Location, data.Notes)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
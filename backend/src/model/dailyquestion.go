@@ -0,0 +1,117 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// 하루 한 번 정해진 시간에 질문을 보내는 커플별 설정
+type DailyQuestionData struct {
+	Connection_id int `json:"-"`
+	Is_enabled int `json:"is_enabled"`
+	Send_time string `json:"send_time"`
+	Timezone string `json:"timezone"`
+	Category string `json:"category"`
+	Last_sent_date string `json:"last_sent_date"`
+}
+
+func GetDailyQuestionByConnID(connection_id int) (DailyQuestionData, bool, error) {
+	dailyQuestionData := DailyQuestionData{Connection_id: connection_id}
+
+	r, err := db.Query(`SELECT is_enabled, send_time, timezone, category, last_sent_date FROM daily_question WHERE connection_id = ?`, connection_id)
+	if err != nil {
+		return dailyQuestionData, false, err
+	}
+	defer r.Close()
+
+	if !r.Next() {
+		return dailyQuestionData, false, nil
+	}
+	err = r.Scan(&dailyQuestionData.Is_enabled, &dailyQuestionData.Send_time, &dailyQuestionData.Timezone, &dailyQuestionData.Category, &dailyQuestionData.Last_sent_date)
+	return dailyQuestionData, err == nil, err
+}
+
+// 설정이 없으면 추가하고 있으면 변경, 마지막으로 보낸 날짜는 유지
+func UpsertDailyQuestion(data DailyQuestionData) error {
+	_, err := db.Exec(`INSERT INTO daily_question (connection_id, is_enabled, send_time, timezone, category) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE is_enabled = VALUES(is_enabled), send_time = VALUES(send_time), timezone = VALUES(timezone), category = VALUES(category)`, data.Connection_id, data.Is_enabled, data.Send_time, data.Timezone, data.Category)
+	return err
+}
+
+func GetEnabledDailyQuestions() ([]DailyQuestionData, error) {
+	r, err := db.Query(`SELECT connection_id, is_enabled, send_time, timezone, category, last_sent_date FROM daily_question WHERE is_enabled = 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	dailyQuestionDatas := []DailyQuestionData{}
+	var dailyQuestionData DailyQuestionData
+	for r.Next() {
+		err = r.Scan(&dailyQuestionData.Connection_id, &dailyQuestionData.Is_enabled, &dailyQuestionData.Send_time, &dailyQuestionData.Timezone, &dailyQuestionData.Category, &dailyQuestionData.Last_sent_date)
+		if err != nil {
+			return nil, err
+		}
+		dailyQuestionDatas = append(dailyQuestionDatas, dailyQuestionData)
+	}
+	return dailyQuestionDatas, nil
+}
+
+// 커플이 아직 받지 않은 활성화된 질문 중 priority가 가장 높은 질문, 없으면 Question_id가 0
+// category가 비어있으면 모든 카테고리에서 고름
+func GetNextDailyQuestion(connection_id int, category string) (QuestionData, error) {
+	var questionData QuestionData
+
+	query := `SELECT q.question_id, q.question_contents, q.answer_options FROM question q WHERE q.is_active = 1 and NOT EXISTS (SELECT 1 FROM answer a WHERE a.connection_id = ? and a.question_id = q.question_id)`
+	args := []interface{}{connection_id}
+	if category != "" {
+		query += ` and q.category = ?`
+		args = append(args, category)
+	}
+
+	r, err := db.Query(query+` ORDER BY q.priority DESC, q.question_id ASC LIMIT 1`, args...)
+	if err != nil {
+		return questionData, err
+	}
+	defer r.Close()
+
+	var answerOptions sql.NullString
+	if r.Next() {
+		err = r.Scan(&questionData.Question_id, &questionData.Question_contents, &answerOptions)
+		questionData.Answer_options = parseAnswerOptions(answerOptions)
+	}
+	return questionData, err
+}
+
+// 오늘의 질문을 answer에 추가하고 보낸 날짜를 기록, question_id가 0이면 날짜만 기록
+func InsertDailyAnswer(answer_date string, connection_id, question_id int, sent_date string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if question_id != 0 {
+		_, err = tx.Exec(`INSERT INTO answer (connection_id, question_id, answer_date, is_daily) VALUES (?, ?, ?, 1)`, connection_id, question_id, answer_date)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`UPDATE daily_question SET last_sent_date = ? WHERE connection_id = ?`, sent_date, connection_id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func GetConnectionByConnID(connection_id int) (string, string, error) {
+	r, err := db.Query(`SELECT first_usr, second_usr FROM connection WHERE connection_id = ?`, connection_id)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	var first_usr, second_usr string
+	if r.Next() {
+		err = r.Scan(&first_usr, &second_usr)
+	}
+	return first_usr, second_usr, err
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"strconv"
+)
+
+// 앨범에 표시되는 사진 하나, chat의 is_image 첨부파일 기준
+type PhotoData struct {
+	Chat_id int `json:"chat_id"`
+	File_name string `json:"file_name"`
+	Mime_type string `json:"mime_type"`
+	File_size int64 `json:"file_size"`
+	Writer_id string `json:"writer_id"`
+	Write_time string `json:"write_time"`
+	Is_favorite bool `json:"is_favorite"`
+}
+
+type AlbumData struct {
+	Album_id int `json:"album_id"`
+	Connection_id int `json:"-"`
+	Album_name string `json:"album_name"`
+	Photo_count int `json:"photo_count"`
+	Cover_chat_id int `json:"cover_chat_id"`
+}
+
+type PhotoMonthData struct {
+	Month string `json:"month"`
+	Count int `json:"count"`
+}
+
+const selectPhoto = `SELECT a.chat_id, a.file_name, a.mime_type, a.file_size, c.writer_id, c.write_time, f.chat_id IS NOT NULL
+	FROM attachment a
+	JOIN chat c ON c.chat_id = a.chat_id
+	LEFT JOIN photo_favorite f ON f.chat_id = a.chat_id AND f.connection_id = a.connection_id `
+
+func scanPhotos(query string, args ...interface{}) ([]PhotoData, error) {
+	r, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var photoData PhotoData
+	var photoDatas []PhotoData
+	for r.Next() {
+		err = r.Scan(&photoData.Chat_id, &photoData.File_name, &photoData.Mime_type, &photoData.File_size, &photoData.Writer_id, &photoData.Write_time, &photoData.Is_favorite)
+		if err != nil {
+			return nil, err
+		}
+		photoDatas = append(photoDatas, photoData)
+	}
+	return photoDatas, nil
+}
+
+// 커플의 사진을 최신순으로 불러오기, month(YYYY-MM)가 있으면 해당 달만, favorite이면 즐겨찾기만
+func GetPhotosByConnID(connection_id int, month string, favorite bool, limit, offset int) ([]PhotoData, error) {
+	query := selectPhoto + `WHERE a.connection_id = ? and c.is_image = 1`
+	args := []interface{}{connection_id}
+	if month != "" {
+		query += ` and DATE_FORMAT(c.write_time, '%Y-%m') = ?`
+		args = append(args, month)
+	}
+	if favorite {
+		query += ` and f.chat_id IS NOT NULL`
+	}
+	query += ` ORDER BY a.chat_id DESC LIMIT ` + strconv.Itoa(limit) + ` OFFSET ` + strconv.Itoa(offset)
+	return scanPhotos(query, args...)
+}
+
+// 달별 사진 개수, 최신 달부터
+func GetPhotoMonthsByConnID(connection_id int) ([]PhotoMonthData, error) {
+	r, err := db.Query(`SELECT DATE_FORMAT(c.write_time, '%Y-%m') AS month, COUNT(*) FROM attachment a JOIN chat c ON c.chat_id = a.chat_id WHERE a.connection_id = ? and c.is_image = 1 GROUP BY month ORDER BY month DESC`, connection_id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var monthData PhotoMonthData
+	var monthDatas []PhotoMonthData
+	for r.Next() {
+		err = r.Scan(&monthData.Month, &monthData.Count)
+		if err != nil {
+			return nil, err
+		}
+		monthDatas = append(monthDatas, monthData)
+	}
+	return monthDatas, nil
+}
+
+// 커플이 올린 사진인지 확인
+func CheckPhotoByConnIDAndChatID(connection_id, chat_id int) (bool, error) {
+	r, err := db.Query(`SELECT a.chat_id FROM attachment a JOIN chat c ON c.chat_id = a.chat_id WHERE a.connection_id = ? and a.chat_id = ? and c.is_image = 1`, connection_id, chat_id)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	return r.Next(), nil
+}
+
+func InsertPhotoFavorite(connection_id, chat_id int) error {
+	_, err := db.Exec(`INSERT IGNORE INTO photo_favorite (connection_id, chat_id) VALUES (?, ?)`, connection_id, chat_id)
+	return err
+}
+
+func DeletePhotoFavorite(connection_id, chat_id int) error {
+	_, err := db.Exec(`DELETE FROM photo_favorite WHERE connection_id = ? and chat_id = ?`, connection_id, chat_id)
+	return err
+}
+
+// 채팅에서 삭제된 사진을 즐겨찾기와 앨범에서도 제거
+func DeletePhotoByChatID(chat_id int) error {
+	_, err := db.Exec(`DELETE FROM photo_favorite WHERE chat_id = ?`, chat_id)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM album_photo WHERE chat_id = ?`, chat_id)
+	return err
+}
+
+func InsertAlbum(connection_id int, album_name string) (int, error) {
+	result, err := db.Exec(`INSERT INTO album (connection_id, album_name) VALUES (?, ?)`, connection_id, album_name)
+	if err != nil {
+		return 0, err
+	}
+	album_id, err := result.LastInsertId()
+	return int(album_id), err
+}
+
+func GetAlbumsByConnID(connection_id int) ([]AlbumData, error) {
+	r, err := db.Query(`SELECT al.album_id, al.album_name, COUNT(p.chat_id), COALESCE(MAX(p.chat_id), 0)
+		FROM album al LEFT JOIN album_photo p ON p.album_id = al.album_id
+		WHERE al.connection_id = ? GROUP BY al.album_id, al.album_name ORDER BY al.album_id ASC`, connection_id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var albumData AlbumData
+	var albumDatas []AlbumData
+	for r.Next() {
+		err = r.Scan(&albumData.Album_id, &albumData.Album_name, &albumData.Photo_count, &albumData.Cover_chat_id)
+		if err != nil {
+			return nil, err
+		}
+		albumData.Connection_id = connection_id
+		albumDatas = append(albumDatas, albumData)
+	}
+	return albumDatas, nil
+}
+
+// 커플이 만든 앨범인지 확인
+func CheckAlbumByConnIDAndAlbumID(connection_id, album_id int) (bool, error) {
+	r, err := db.Query(`SELECT album_id FROM album WHERE connection_id = ? and album_id = ?`, connection_id, album_id)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	return r.Next(), nil
+}
+
+func UpdateAlbumName(album_id int, album_name string) error {
+	_, err := db.Exec(`UPDATE album SET album_name = ? WHERE album_id = ?`, album_name, album_id)
+	return err
+}
+
+func DeleteAlbumByAlbumID(album_id int) error {
+	_, err := db.Exec(`DELETE FROM album_photo WHERE album_id = ?`, album_id)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM album WHERE album_id = ?`, album_id)
+	return err
+}
+
+func InsertAlbumPhoto(album_id, chat_id int) error {
+	_, err := db.Exec(`INSERT IGNORE INTO album_photo (album_id, chat_id) VALUES (?, ?)`, album_id, chat_id)
+	return err
+}
+
+func DeleteAlbumPhoto(album_id, chat_id int) error {
+	_, err := db.Exec(`DELETE FROM album_photo WHERE album_id = ? and chat_id = ?`, album_id, chat_id)
+	return err
+}
+
+func GetPhotosByAlbumID(album_id, limit, offset int) ([]PhotoData, error) {
+	query := selectPhoto + `JOIN album_photo p ON p.chat_id = a.chat_id WHERE p.album_id = ? ORDER BY a.chat_id DESC LIMIT ` + strconv.Itoa(limit) + ` OFFSET ` + strconv.Itoa(offset)
+	return scanPhotos(query, album_id)
+}
+
+// attachment 테이블이 생기기 전에 보낸 사진 채팅, 첨부파일 레코드가 없어서 갤러리에 보이지 않음
+func GetImageChatsWithoutAttachment() ([]AttachmentData, error) {
+	r, err := db.Query(`SELECT c.chat_id, u.conn_id, c.text_body FROM chat c JOIN usrs u ON u.uuid = c.writer_id LEFT JOIN attachment a ON a.chat_id = c.chat_id WHERE c.is_image = 1 and a.chat_id IS NULL and u.conn_id <> 0`)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var attachmentData AttachmentData
+	var attachmentDatas []AttachmentData
+	for r.Next() {
+		err = r.Scan(&attachmentData.Chat_id, &attachmentData.Connection_id, &attachmentData.File_name)
+		if err != nil {
+			return nil, err
+		}
+		attachmentDatas = append(attachmentDatas, attachmentData)
+	}
+	return attachmentDatas, nil
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+const selectChatWithAttachment = `SELECT c.chat_id, c.writer_id, c.write_time, c.text_body, c.is_file, c.is_image, c.is_voice, COALESCE(a.duration_ms, 0), COALESCE(a.waveform, '')
+	FROM chat c LEFT JOIN attachment a ON a.chat_id = c.chat_id `
+
+func scanChats(r *sql.Rows) ([]ChatData, error) {
+	var chatData ChatData
+	var chatDatas []ChatData
+	var waveform string
+	for r.Next() {
+		err := r.Scan(&chatData.Chat_id, &chatData.Writer_id, &chatData.Write_time, &chatData.Text_body, &chatData.Is_file, &chatData.Is_image, &chatData.Is_voice, &chatData.Duration_ms, &waveform)
+		if err != nil {
+			return nil, err
+		}
+		chatData.Waveform = parseWaveform(waveform)
+		chatData.Is_system = 0
+		if IsSystemWriterID(chatData.Writer_id) {
+			chatData.Is_system = 1
+		}
+		chatDatas = append(chatDatas, chatData)
+	}
+	return chatDatas, nil
+}
+
+func writerCondition(writer_ids []string) (string, []interface{}) {
+	args := []interface{}{}
+	for _, writer_id := range writer_ids {
+		args = append(args, writer_id)
+	}
+	return `c.writer_id IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(writer_ids)), ", ") + `)`, args
+}
+
+// 커플의 채팅인지 확인
+func CheckChatByWriterIDs(writer_ids []string, chat_id int) (bool, error) {
+	condition, args := writerCondition(writer_ids)
+	r, err := db.Query(`SELECT c.chat_id FROM chat c WHERE `+condition+` and c.chat_id = `+strconv.Itoa(chat_id), args...)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	return r.Next(), nil
+}
+
+// chat_id 이전 채팅 before개와 chat_id를 포함한 이후 채팅 after+1개를 오래된 순으로 리턴
+func GetChatsAroundChatID(writer_ids []string, chat_id, before, after int) ([]ChatData, []ChatData, error) {
+	condition, args := writerCondition(writer_ids)
+
+	r1, err := db.Query(selectChatWithAttachment+`WHERE `+condition+` and c.chat_id < `+strconv.Itoa(chat_id)+` ORDER BY c.chat_id DESC LIMIT `+strconv.Itoa(before), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	previousChats, err := scanChats(r1)
+	r1.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	// 최신순으로 불러왔으므로 뒤집어서 오래된 순으로 맞춤
+	for i, j := 0, len(previousChats)-1; i < j; i, j = i+1, j-1 {
+		previousChats[i], previousChats[j] = previousChats[j], previousChats[i]
+	}
+
+	r2, err := db.Query(selectChatWithAttachment+`WHERE `+condition+` and c.chat_id >= `+strconv.Itoa(chat_id)+` ORDER BY c.chat_id ASC LIMIT `+strconv.Itoa(after+1), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r2.Close()
+	nextChats, err := scanChats(r2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return previousChats, nextChats, nil
+}
+
+// from 이상 to 미만(DB 시간 형식)에 작성된 채팅을 오래된 순으로 불러오고, 전체 개수도 같이 리턴
+func GetChatsInRange(writer_ids []string, from, to string, limit, offset int) ([]ChatData, int, error) {
+	condition, args := writerCondition(writer_ids)
+	where := `WHERE ` + condition + ` and c.write_time >= ? and c.write_time < ?`
+	args = append(args, from, to)
+
+	r1, err := db.Query(`SELECT COUNT(*) FROM chat c `+where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := 0
+	if r1.Next() {
+		err = r1.Scan(&total)
+	}
+	r1.Close()
+	if err != nil || total == 0 {
+		return nil, total, err
+	}
+
+	r2, err := db.Query(selectChatWithAttachment+where+` ORDER BY c.chat_id ASC LIMIT `+strconv.Itoa(limit)+` OFFSET `+strconv.Itoa(offset), args...)
+	if err != nil {
+		return nil, total, err
+	}
+	defer r2.Close()
+
+	chatDatas, err := scanChats(r2)
+	return chatDatas, total, err
+}
+
+// from 이상 to 미만에 작성된 채팅 개수를 시간(YYYY-MM-DD HH:00:00) 단위로 묶어서 리턴
+// 날짜별 개수는 요청한 시간대로 바꿔서 세야 하므로 시간 단위까지만 DB에서 묶음
+func GetHourlyChatCounts(writer_ids []string, from, to string) (map[string]int, error) {
+	condition, args := writerCondition(writer_ids)
+	args = append(args, from, to)
+
+	r, err := db.Query(`SELECT DATE_FORMAT(c.write_time, '%Y-%m-%d %H:00:00') AS hour, COUNT(*) FROM chat c WHERE `+condition+` and c.write_time >= ? and c.write_time < ? GROUP BY hour`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	counts := make(map[string]int)
+	var hour string
+	var count int
+	for r.Next() {
+		err = r.Scan(&hour, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[hour] = count
+	}
+	return counts, nil
+}
+
+// from 이상 to 미만에 작성된 채팅을 시간순으로 리턴, 통계 계산에 필요한 값만 불러옴
+func GetChatsForStats(writer_ids []string, from, to string) ([]ChatData, error) {
+	condition, args := writerCondition(writer_ids)
+	args = append(args, from, to)
+
+	r, err := db.Query(`SELECT c.writer_id, c.write_time, IF(c.is_file = 1, "", c.text_body), c.is_file, c.is_image, c.is_voice FROM chat c WHERE `+condition+` and c.write_time >= ? and c.write_time < ? ORDER BY c.write_time ASC, c.chat_id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	chatDatas := []ChatData{}
+	var chatData ChatData
+	for r.Next() {
+		err = r.Scan(&chatData.Writer_id, &chatData.Write_time, &chatData.Text_body, &chatData.Is_file, &chatData.Is_image, &chatData.Is_voice)
+		if err != nil {
+			return nil, err
+		}
+		chatDatas = append(chatDatas, chatData)
+	}
+	return chatDatas, nil
+}
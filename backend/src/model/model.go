@@ -3,9 +3,7 @@ package model
 import (
 	"database/sql"
 	"fmt"
-	"regexp"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
@@ -21,6 +19,11 @@ type ChatData struct {
 	Is_deleted int `json:"is_deleted"`
 	Is_file int `json:"is_file"`
 	Is_image int `json:"is_image"`
+	Is_voice int `json:"is_voice"`
+	Duration_ms int `json:"duration_ms,omitempty"`
+	Waveform []int `json:"waveform,omitempty"`
+	Answer_options []string `json:"answer_options,omitempty"`
+	Is_system int `json:"is_system,omitempty"` // 일정 알림처럼 서버가 보낸 메시지
 }
 
 type RequestData struct {
@@ -42,9 +45,14 @@ type UsrsData struct {
 }
 
 type QuestionData struct {
-	Question_id int
-	Target_word string
-	Question_contents string
+	Question_id int `json:"question_id"`
+	Question_contents string `json:"question_contents"`
+	Trigger_words []string `json:"trigger_words"`
+	Category string `json:"category"`
+	Language string `json:"language"`
+	Is_active int `json:"is_active"`
+	Priority int `json:"priority"`
+	Answer_options []string `json:"answer_options,omitempty"`
 }
 
 type AnswerData struct {
@@ -56,6 +64,8 @@ type AnswerData struct {
 	AnswerDate string `json:"answer_date"`
 	Question_id int
 	Order int `json:"order"`
+	Category string `json:"category,omitempty"`
+	Answer_options []string `json:"answer_options,omitempty"`
 }
 
 type BeAboutToDeleteData struct {
@@ -71,6 +81,21 @@ type AnniversaryData struct {
 	Date int `json:"date"`
 	Contents string `json:"contents"`
 	D_day bool `json:"d_day"`
+	Recurrence string `json:"recurrence"` // ""(한 번), yearly, monthly, days(interval_days일마다)
+	Interval_days int `json:"interval_days"`
+	Is_lunar bool `json:"is_lunar"` // year, month, date가 음력
+	Is_leap_month bool `json:"is_leap_month"`
+	Is_milestone bool `json:"is_milestone"` // 사귄 날부터 자동으로 만든 100일, 1주년 같은 기념일
+	Origin_date string `json:"origin_date,omitempty"` // 반복 일정을 펼쳤을 때 저장된 원래 날짜 (YYYY-MM-DD)
+	// DB에는 서버 시간(Asia/Seoul)으로 저장하고 end_time은 포함하지 않는 끝 시간
+	// 응답에서는 timezone 기준 RFC3339, 하루 종일 일정은 날짜(YYYY-MM-DD)이고 end_time은 마지막 날
+	Start_time string `json:"start_time"`
+	End_time string `json:"end_time"`
+	Is_all_day bool `json:"is_all_day"`
+	Timezone string `json:"timezone"`
+	Location string `json:"location"`
+	Notes string `json:"notes"`
+	Reminders []int `json:"reminders,omitempty"` // 일정 시작 몇 분 전에 채팅으로 알림을 보낼지
 }
 
 var db *sql.DB
@@ -368,20 +393,27 @@ func GetConnectionByUsrsUUID(uuid string) (string, string, int, error) {
 	return first_uuid, second_uuid, conn_id, nil
 }
 
-func SelectChatByUsrsUUID(first_uuid, second_uuid string) ([]ChatData, error) {
+// 커플 두 사람의 채팅과 커플에게 보낸 시스템 메시지(일정 알림)
+func SelectChatByUsrsUUID(first_uuid, second_uuid string, conn_id int) ([]ChatData, error) {
 	initialChat := ChatData{}
 	initialChats := []ChatData{}
 
-	r, err := db.Query(`SELECT chat_id, writer_id, write_time, text_body, is_file, is_image FROM chat WHERE writer_id = "`+first_uuid+`" or writer_id = "`+second_uuid+`" ORDER BY chat_id ASC`)
+	r, err := db.Query(`SELECT c.chat_id, c.writer_id, c.write_time, c.text_body, c.is_file, c.is_image, c.is_voice, COALESCE(a.duration_ms, 0), COALESCE(a.waveform, '') FROM chat c LEFT JOIN attachment a ON a.chat_id = c.chat_id WHERE c.writer_id = "`+first_uuid+`" or c.writer_id = "`+second_uuid+`" or c.writer_id = "`+SystemWriterID(conn_id)+`" ORDER BY c.chat_id ASC`)
 	defer r.Close()
 	if err != nil {
 		return nil, err
 	}
 
+	var waveform string
 	for r.Next() {
-		r.Scan(&initialChat.Chat_id, &initialChat.Writer_id, &initialChat.Write_time, &initialChat.Text_body, &initialChat.Is_file, &initialChat.Is_image)
+		r.Scan(&initialChat.Chat_id, &initialChat.Writer_id, &initialChat.Write_time, &initialChat.Text_body, &initialChat.Is_file, &initialChat.Is_image, &initialChat.Is_voice, &initialChat.Duration_ms, &waveform)
 		initialChat.Is_deleted = 0
 		initialChat.Is_answer = 0
+		initialChat.Waveform = parseWaveform(waveform)
+		initialChat.Is_system = 0
+		if IsSystemWriterID(initialChat.Writer_id) {
+			initialChat.Is_system = 1
+		}
 		initialChats = append(initialChats, initialChat)		
 	}
 	return initialChats, nil
@@ -419,26 +451,11 @@ func CheckAnswerByConnIDandQuestionID(connection_id, question_id int) (bool, err
 	return false, nil
 }
 
-func UpdateFirstAnswerByQuestionID(first_answer string, question_id int) error {
-	_, err := db.Query(`UPDATE answer SET first_answer = "`+first_answer+`" WHERE question_id = `+strconv.Itoa(question_id))
-	return err
-}
-
-func UpdateSecondAnswerByQuestionID(first_answer string, question_id int) error {
-	_, err := db.Query(`UPDATE answer SET second_answer = "`+first_answer+`" WHERE question_id = `+strconv.Itoa(question_id))
-	return err
-}
-
 func InsertAnswer(answer_date string, connection_id, question_id int) error {
 	_, err := db.Query(`INSERT INTO answer (connection_id, question_id, answer_date) VALUES (`+strconv.Itoa(connection_id)+`,`+strconv.Itoa(question_id)+`, "`+answer_date+`")`)
 	return err
 }
 
-func SelectQuetions() (*sql.Rows, error) {
-	r, err := db.Query("SELECT target_word, question_id, question_contents FROM question ORDER BY question_id ASC")
-	return r, err
-}
-
 func GetUsrOrderByUUID(uuid string) (int, error) {
 	r, err := db.Query(`SELECT order_usr FROM usrs WHERE uuid = "`+uuid+`"`)
 	defer r.Close()
@@ -452,56 +469,8 @@ func GetUsrOrderByUUID(uuid string) (int, error) {
 	return order_usr, nil
 }
 
-func QuestionIDOfEmptyAnswerByOrder(order, connection_id int) (int, error) {
-	var question_id int
-
-	if order == 1 {
-		r, err1 := db.Query(`SELECT question_id FROM ANSWER WHERE first_answer = "not-written" and connection_id = `+strconv.Itoa(connection_id))
-		defer r.Close()
-		if err1 != nil {
-			return 0, err1
-		}
-		if r.Next() {
-			r.Scan(&question_id)
-		} else {
-			return 0, nil
-		}
-	} else {
-		r, err2 := db.Query(`SELECT question_id FROM ANSWER WHERE second_answer = "not-written" and connection_id = `+strconv.Itoa(connection_id))
-		defer r.Close()
-
-		if err2 != nil {
-			return 0, err2
-		}
-		if r.Next() {
-			r.Scan(&question_id)
-		} else {
-			return 0, nil
-		}
-	}
-	return question_id, nil
-}
-
-func GetQuestionByQuestionID(questionID int) (string, string, error){
-	var questionData QuestionData
-
-	r, err1 := db.Query(`SELECT target_word, question_contents FROM question WHERE question_id = `+ strconv.Itoa(questionID))
-	defer r.Close()
-	if err1 != nil {
-		return "", "", err1
-	}
-	
-	r.Next()
-	err2 := r.Scan(&questionData.Target_word, &questionData.Question_contents)
-	if err2 != nil {
-		return "", "", err2
-	}
-
-	return questionData.Target_word, questionData.Question_contents, nil
-}
-
 func GetRecentAnswerByConnID(connection_id, num int) []AnswerData {
-	r, err := db.Query("SELECT * FROM answer ORDER BY answer_id DESC LIMIT " + strconv.Itoa(num))
+	r, err := db.Query("SELECT answer_id, connection_id, first_answer, second_answer, answer_date, question_id FROM answer WHERE connection_id = " + strconv.Itoa(connection_id) + " ORDER BY answer_id DESC LIMIT " + strconv.Itoa(num))
 	defer r.Close()
 	if err != nil {
 		fmt.Println("ERROR #55 : ", err.Error())
@@ -516,101 +485,53 @@ func GetRecentAnswerByConnID(connection_id, num int) []AnswerData {
 	return answerDatas
 }
 
-func GetFrequentWords(uuid string, rankNum int) ([]string, error) {
-	r, err := db.Query(`SELECT text_body FROM chat WHERE writer_id = "`+uuid+`" and DATE_ADD(NOW(), INTERVAL -7 DAY) < write_time`)
-	defer r.Close()
-	if err != nil {
-		fmt.Println("ERROR #56 : ", err.Error())
-	}
-
-	var recentChat string
-	var recentChats []string
-	for r.Next() {
-		r.Scan(&recentChat)
-		recentChats = append(recentChats, recentChat)
-	}
-
-	chatSum := strings.Join(recentChats, " ")
-	regexpKorean := regexp.MustCompile("[^가-힣]+")
-	onlyKorean := regexpKorean.ReplaceAllString(chatSum, " ")
-
-	addWordTimes := make(map[string]int)
-
-	wordSlice := strings.Split(onlyKorean, " ")
-
-	for i := 0; i < len(wordSlice); i++ {
-		addWordTimes[wordSlice[i]] = addWordTimes[wordSlice[i]] + 1
-	}
-
-	var withOutRepeat string
+// 랭킹 제외 단어, uuid가 빈 값이면 커플이 같이 쓰는 목록이고 아니면 그 사람만 적용되는 목록
+// except_word의 *는 아무 글자와 일치 ("사랑*"은 "사랑"으로 시작하는 모든 단어)
+type ExceptWordData struct {
+	Except_word string `json:"except_word"`
+	Scope string `json:"scope"`
+}
 
-	for i := 0; i < len(wordSlice); i++ {
-		if !strings.Contains(withOutRepeat, wordSlice[i]) {
-			withOutRepeat += " "+wordSlice[i]
+// 제외 단어들을 추가하고 새로 추가된 단어만 리턴, 이미 있는 단어는 건너뜀
+func InsertExceptWords(connection_id int, uuid string, except_words []string) ([]string, error) {
+	added := []string{}
+	for _, except_word := range except_words {
+		result, err := db.Exec(`INSERT IGNORE INTO exceptionword (connection_id, uuid, except_word) VALUES (?, ?, ?)`, connection_id, uuid, except_word)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	conn_id, err := SelectConnIDByUUID(uuid)
-
-	exceptWordsSlice, err := GetExceptWords(conn_id)
-	if err != nil {
-		return nil, err
-	}
-	for i := 0; i < len(exceptWordsSlice); i++ {
-		withOutRepeat = strings.ReplaceAll(withOutRepeat, exceptWordsSlice[i], "")
-	}
-
-	withOutRepeatSlice := strings.Fields(withOutRepeat)
-
-	if len(withOutRepeatSlice) < rankNum {
-		return nil, err
-	}
-
-	// bubble sort
-	for i := 0; i < len(withOutRepeatSlice)-1; i++ {
-		for i := len(withOutRepeatSlice)-1; i > 0; i-- {
-			if addWordTimes[withOutRepeatSlice[i]] > addWordTimes[withOutRepeatSlice[i-1]] {
-				temp := withOutRepeatSlice[i-1]
-				withOutRepeatSlice[i-1] = withOutRepeatSlice[i]
-				withOutRepeatSlice[i] = temp
-			}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected > 0 {
+			added = append(added, except_word)
 		}
 	}
-	return withOutRepeatSlice[:rankNum], nil
-}
-
-func InsertExceptWord(connection_id int, except_word string) error {
-	_, err := db.Query(`INSERT INTO exceptionword (connection_id, except_word) VALUES (`+strconv.Itoa(connection_id)+`, "`+except_word+`")`)
-	return err
-}
-
-func CheckWordAlreadyExcepted(connection_id int, except_word string) (bool, error) {
-	r, err := db.Query(`SELECT * FROM exceptionword WHERE connection_id = `+strconv.Itoa(connection_id)+` and except_word = "`+except_word+`"`)
-	defer r.Close()
-
-	if r.Next() {
-		return true, nil
-	} else {
-		return false, err
-	}
+	return added, nil
 }
 
-func CancleExceptWord(connection_id int, except_word string) error {
-	_, err := db.Query(`DELETE FROM exceptionword WHERE connection_id = `+strconv.Itoa(connection_id)+` and except_word = "`+except_word+`"`)
+// 커플 목록과 내 목록에서 제외 단어 삭제, 상대방 목록은 건드리지 않음
+func CancleExceptWord(connection_id int, uuid, except_word string) error {
+	_, err := db.Exec(`DELETE FROM exceptionword WHERE connection_id = ? and uuid IN ("", ?) and except_word = ?`, connection_id, uuid, except_word)
 	return err
 }
 
-func GetExceptWords(connection_id int) ([]string, error) {
-	r, err := db.Query("SELECT except_word FROM exceptionword WHERE connection_id = "+strconv.Itoa(connection_id))
-	defer r.Close()
+// 커플 목록과 내 목록의 제외 단어
+func GetExceptWords(connection_id int, uuid string) ([]ExceptWordData, error) {
+	r, err := db.Query(`SELECT except_word, IF(uuid = "", "couple", "me") FROM exceptionword WHERE connection_id = ? and uuid IN ("", ?) ORDER BY uuid ASC, except_word ASC`, connection_id, uuid)
 	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
 
-	var exceptWord string
-	var exceptWords []string
+	exceptWords := []ExceptWordData{}
+	var exceptWord ExceptWordData
 	for r.Next() {
-		r.Scan(&exceptWord)
+		err = r.Scan(&exceptWord.Except_word, &exceptWord.Scope)
+		if err != nil {
+			return nil, err
+		}
 		exceptWords = append(exceptWords, exceptWord)
 	}
 	return exceptWords, nil
@@ -626,17 +547,47 @@ func InsertBeAboutToDelete(connection_id int) error {
 	return err
 }
 
+// 커넥션과 커플의 데이터를 모두 지우고 두 사람의 conn_id, order_usr를 초기화
+// 하나라도 실패하면 전부 되돌려서 반쯤 지워진 커넥션에 두 사람이 묶여 있지 않게 함
 func DeleteConnectionByConnID(first_uuid, second_uuid string, conn_id int) error {
-	_, err := db.Query(`DELETE FROM chat WHERE writer_id = "`+first_uuid+`" or writer_id = "`+second_uuid+`"`)
-	_, err = db.Query("DELETE FROM connection WHERE connection_id = "+strconv.Itoa(conn_id))
-	_, err = db.Query("DELETE FROM beaboutdelete WHERE connection_id = "+strconv.Itoa(conn_id))
-	_, err = db.Query("DELETE FROM answer WHERE connection_id = "+strconv.Itoa(conn_id))
-	_, err = db.Query("DELETE FROM exceptionword WHERE connection_id = "+strconv.Itoa(conn_id))
-	_, err = db.Query("DELETE FROM anniversary WHERE connection_id = "+strconv.Itoa(conn_id))
-	_, err = db.Query(`UPDATE usrs SET conn_id = 0  WHERE uuid = "`+first_uuid+`" or uuid = "`+second_uuid+`"`)
-	_, err = db.Query(`UPDATE usrs SET order_usr = 0 WHERE uuid = "`+first_uuid+`" or uuid = "`+second_uuid+`"`)
-
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args []interface{}
+	}{
+		{`DELETE FROM chat WHERE writer_id = ? or writer_id = ?`, []interface{}{first_uuid, second_uuid}},
+		{`DELETE FROM connection WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM beaboutdelete WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM answer WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM exceptionword WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM anniversary WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM attachment WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM upload WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM photo_favorite WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM album_photo WHERE album_id IN (SELECT album_id FROM album WHERE connection_id = ?)`, []interface{}{conn_id}},
+		{`DELETE FROM album WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM daily_question WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM pending_question WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM word_count WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM chat_sentiment WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM mood_setting WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM calendar_feed WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM anniversary_reminder WHERE connection_id = ?`, []interface{}{conn_id}},
+		{`DELETE FROM chat WHERE writer_id = ?`, []interface{}{SystemWriterID(conn_id)}},
+		{`UPDATE usrs SET conn_id = 0, order_usr = 0 WHERE uuid = ? or uuid = ?`, []interface{}{first_uuid, second_uuid}},
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(statement.query, statement.args...)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func ChangePassword(password, uuid string) error {
@@ -649,31 +600,69 @@ func DeleteChatByChatID(chat_id int) error {
 	return err
 }
 
-func InsertAnniversaryByConnID(data AnniversaryData) error {
-	_, err := db.Query(`INSERT INTO anniversary (connection_id, year, month, date, contents, d_day) Values (`+strconv.Itoa(data.Connection_id)+`, `+strconv.Itoa(data.Year)+`, `+strconv.Itoa(data.Month)+`, `+strconv.Itoa(data.Date)+`, "`+data.Contents+`", `+strconv.FormatBool(data.D_day)+`)`)
-	return err
-}
-
-func GetAnniversaryByConnIDAndMonthAndYear(connection_id int, target_month, target_year string) ([]AnniversaryData, error) {
-	r, err := db.Query(`SELECT * FROM anniversary WHERE connection_id = `+strconv.Itoa(connection_id)+` and month = "`+target_month+`" and year = "`+target_year+`"`)
-	defer r.Close()
+func InsertAnniversaryByConnID(data AnniversaryData) (int, error) {
+	result, err := db.Exec(`INSERT INTO anniversary (connection_id, year, month, date, contents, d_day, recurrence, interval_days, is_lunar, is_leap_month, start_time, end_time, is_all_day, timezone, location, notes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, data.Connection_id, data.Year, data.Month, data.Date, data.Contents, data.D_day, data.Recurrence, data.Interval_days, data.Is_lunar, data.Is_leap_month, data.Start_time, data.End_time, data.Is_all_day, data.Timezone, data.Location, data.Notes)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	anniversary_id, err := result.LastInsertId()
+	return int(anniversary_id), err
+}
 
+const anniversaryColumns = `a.anniversary_id, a.connection_id, a.year, a.month, a.date, a.contents, a.d_day, a.recurrence, a.interval_days, a.is_lunar, a.is_leap_month, a.start_time, a.end_time, a.is_all_day, a.timezone, a.location, a.notes`
+
+const selectAnniversary = `SELECT `+anniversaryColumns+` FROM anniversary a `
+
+// anniversaryColumns 순서대로 Scan할 필드들
+func anniversaryScanArgs(data *AnniversaryData) []interface{} {
+	return []interface{}{&data.Anniversary_id, &data.Connection_id, &data.Year, &data.Month, &data.Date, &data.Contents, &data.D_day, &data.Recurrence, &data.Interval_days, &data.Is_lunar, &data.Is_leap_month, &data.Start_time, &data.End_time, &data.Is_all_day, &data.Timezone, &data.Location, &data.Notes}
+}
+
+func scanAnniversaries(r *sql.Rows) ([]AnniversaryData, error) {
 	var anniversaryData AnniversaryData
 	var anniversaryDatas []AnniversaryData
-
 	for r.Next() {
-		r.Scan(&anniversaryData.Anniversary_id, &anniversaryData.Connection_id, &anniversaryData.Year, &anniversaryData.Month, &anniversaryData.Date, &anniversaryData.Contents, &anniversaryData.D_day)
+		err := r.Scan(anniversaryScanArgs(&anniversaryData)...)
+		if err != nil {
+			return nil, err
+		}
 		anniversaryDatas = append(anniversaryDatas, anniversaryData)
 	}
-
 	return anniversaryDatas, nil
 }
 
-func DeleteAnniversaryByAnniversaryID(anniversary_id string) error {
-	_, err := db.Query("DELETE FROM anniversary WHERE anniversary_id = "+anniversary_id)
+// from 이상 to 미만(DB 시간 형식)과 겹치는 일정과 모든 반복 일정, 반복 일정은 원래 날짜 그대로 리턴
+func GetAnniversariesInRange(connection_id int, from, to string) ([]AnniversaryData, error) {
+	r, err := db.Query(selectAnniversary+`WHERE connection_id = ? and (recurrence != "" or (start_time < ? and end_time > ?)) ORDER BY start_time ASC, anniversary_id ASC`, connection_id, to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return scanAnniversaries(r)
+}
+
+// 커플이 연결된 날 (YYYY/MM/DD)
+func GetConnectionStartDate(connection_id int) (string, error) {
+	r, err := db.Query(`SELECT start_date FROM connection WHERE connection_id = ?`, connection_id)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var start_date string
+	if r.Next() {
+		err = r.Scan(&start_date)
+	}
+	return start_date, err
+}
+
+func DeleteAnniversaryByAnniversaryID(connection_id, anniversary_id int) error {
+	_, err := db.Exec(`DELETE FROM anniversary WHERE anniversary_id = ? AND connection_id = ?`, anniversary_id, connection_id)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM anniversary_reminder WHERE anniversary_id = ? AND connection_id = ?`, anniversary_id, connection_id)
 	return err
 }
 
@@ -698,20 +687,13 @@ func ChangeDDayZeroByAnniversaryID(anniversary_id int) error {
 }
 
 func GetDDayByConnID(connection_id int) ([]AnniversaryData, error){
-	r, err := db.Query("SELECT * FROM anniversary WHERE d_day = 1 and connection_id = "+strconv.Itoa(connection_id))
+	r, err := db.Query(selectAnniversary+`WHERE d_day = 1 and connection_id = ? LIMIT 1`, connection_id)
 	if err != nil {
 		return nil, err
 	}
+	defer r.Close()
 
-	var anniversaryData []AnniversaryData
-	var tempData AnniversaryData
-	if r.Next(){
-		r.Scan(&tempData.Anniversary_id, &tempData.Connection_id, &tempData.Year, &tempData.Month, &tempData.Date, &tempData.Contents, &tempData.D_day)
-		anniversaryData = append(anniversaryData, tempData)
-		return anniversaryData, nil
-	}
-
-	return nil, nil
+	return scanAnniversaries(r)
 }
 
 func GetChatIDFromRecentFileChatByUUID(uuid string) (int, error) {
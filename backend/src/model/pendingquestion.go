@@ -0,0 +1,74 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// 내가 아직 답하지 않은 질문, 접속하면 순서대로 전달됨
+type PendingQuestionData struct {
+	Question_id int `json:"question_id"`
+	Question_contents string `json:"question_contents"`
+	Answer_date string `json:"answer_date"`
+	Is_daily int `json:"is_daily"`
+	Status string `json:"status"`
+	Defer_until string `json:"defer_until,omitempty"`
+	Answer_options []string `json:"answer_options,omitempty"`
+}
+
+func answerColumn(is_first bool) string {
+	if is_first {
+		return "first_answer"
+	}
+	return "second_answer"
+}
+
+// 내가 답하지 않은 질문을 받은 순서대로 리턴, 미룬 질문은 미룬 시간이 지나면 그 시간 기준으로 뒤에 붙음
+// 건너뛴 질문은 제외하고, includeDeferred면 아직 미룬 시간이 안 된 질문도 포함
+func GetPendingQuestions(connection_id int, uuid string, is_first bool, now string, includeDeferred bool) ([]PendingQuestionData, error) {
+	condition := `(s.status IS NULL or (s.status = "deferred" and s.defer_until <= ?))`
+	args := []interface{}{uuid, connection_id, now}
+	if includeDeferred {
+		condition = `(s.status IS NULL or s.status = "deferred")`
+		args = args[:2]
+	}
+
+	r, err := db.Query(`SELECT a.question_id, q.question_contents, q.answer_options, a.answer_date, a.is_daily, IFNULL(s.status, "pending"), IFNULL(s.defer_until, "") FROM answer a JOIN question q ON a.question_id = q.question_id LEFT JOIN pending_question s ON s.connection_id = a.connection_id and s.question_id = a.question_id and s.uuid = ? WHERE a.connection_id = ? and a.`+answerColumn(is_first)+` = "not-written" and `+condition+` ORDER BY IFNULL(s.defer_until, a.answer_date) ASC, a.answer_id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	pendingQuestionDatas := []PendingQuestionData{}
+	var pendingQuestionData PendingQuestionData
+	var answerOptions sql.NullString
+	for r.Next() {
+		err = r.Scan(&pendingQuestionData.Question_id, &pendingQuestionData.Question_contents, &answerOptions, &pendingQuestionData.Answer_date, &pendingQuestionData.Is_daily, &pendingQuestionData.Status, &pendingQuestionData.Defer_until)
+		if err != nil {
+			return nil, err
+		}
+		pendingQuestionData.Answer_options = parseAnswerOptions(answerOptions)
+		// 미룬 시간이 지난 질문은 다시 대기 중인 질문
+		if pendingQuestionData.Status == "deferred" && pendingQuestionData.Defer_until <= now {
+			pendingQuestionData.Status = "pending"
+		}
+		pendingQuestionDatas = append(pendingQuestionDatas, pendingQuestionData)
+	}
+	return pendingQuestionDatas, nil
+}
+
+// 내가 답하지 않은 질문을 건너뛰거나(skipped) defer_until까지 미룸(deferred), 답하지 않은 질문이 아니면 false 리턴
+// 건너뛸 때는 defer_until을 nil로 보냄
+func UpdatePendingQuestionStatus(connection_id, question_id int, uuid string, is_first bool, status string, defer_until interface{}) (bool, error) {
+	r, err := db.Query(`SELECT 1 FROM answer WHERE connection_id = ? and question_id = ? and `+answerColumn(is_first)+` = "not-written"`, connection_id, question_id)
+	if err != nil {
+		return false, err
+	}
+	isPending := r.Next()
+	r.Close()
+	if !isPending {
+		return false, nil
+	}
+
+	_, err = db.Exec(`INSERT INTO pending_question (connection_id, question_id, uuid, status, defer_until) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE status = VALUES(status), defer_until = VALUES(defer_until)`, connection_id, question_id, uuid, status, defer_until)
+	return err == nil, err
+}
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// 관리자 질문 목록 조회 조건, 빈 값이면 조건 없음
+type QuestionFilter struct {
+	Category string
+	Language string
+	Is_active int // -1이면 활성/비활성 모두
+}
+
+// 질문과 트리거 단어들을 한 트랜잭션으로 저장하고 생성된 question_id들을 리턴
+// 하나라도 실패하면 모두 저장하지 않음
+func InsertQuestions(questions []QuestionData) ([]int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	questionIDs := []int{}
+	for _, question := range questions {
+		result, err := tx.Exec(`INSERT INTO question (question_contents, category, language, is_active, priority, answer_options) VALUES (?, ?, ?, ?, ?, ?)`, question.Question_contents, question.Category, question.Language, question.Is_active, question.Priority, marshalAnswerOptions(question.Answer_options))
+		if err != nil {
+			return nil, err
+		}
+		questionID, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		err = insertQuestionTriggers(tx, int(questionID), question.Trigger_words)
+		if err != nil {
+			return nil, err
+		}
+		questionIDs = append(questionIDs, int(questionID))
+	}
+	return questionIDs, tx.Commit()
+}
+
+// 객관식 보기를 JSON 배열로 저장, 보기가 없는 주관식 질문은 NULL
+func marshalAnswerOptions(answer_options []string) interface{} {
+	if len(answer_options) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(answer_options)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
+
+func parseAnswerOptions(answer_options sql.NullString) []string {
+	if !answer_options.Valid || answer_options.String == "" {
+		return nil
+	}
+	var options []string
+	if json.Unmarshal([]byte(answer_options.String), &options) != nil {
+		return nil
+	}
+	return options
+}
+
+func insertQuestionTriggers(tx *sql.Tx, question_id int, trigger_words []string) error {
+	for _, word := range trigger_words {
+		_, err := tx.Exec(`INSERT INTO question_trigger (question_id, trigger_word) VALUES (?, ?)`, question_id, word)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 질문 내용과 트리거 단어 전체를 교체, 질문이 없으면 false 리턴
+func UpdateQuestion(question QuestionData) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	r, err := tx.Query(`SELECT question_id FROM question WHERE question_id = ? FOR UPDATE`, question.Question_id)
+	if err != nil {
+		return false, err
+	}
+	isExist := r.Next()
+	r.Close()
+	if !isExist {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`UPDATE question SET question_contents = ?, category = ?, language = ?, is_active = ?, priority = ?, answer_options = ? WHERE question_id = ?`, question.Question_contents, question.Category, question.Language, question.Is_active, question.Priority, marshalAnswerOptions(question.Answer_options), question.Question_id)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(`DELETE FROM question_trigger WHERE question_id = ?`, question.Question_id)
+	if err != nil {
+		return false, err
+	}
+	err = insertQuestionTriggers(tx, question.Question_id, question.Trigger_words)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// 질문 활성/비활성 변경, 이미 받은 답변을 보존하기 위해 삭제 대신 비활성화
+func UpdateQuestionActive(question_id, is_active int) (bool, error) {
+	result, err := db.Exec(`UPDATE question SET is_active = ? WHERE question_id = ?`, is_active, question_id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected > 0 {
+		return true, nil
+	}
+	// 값이 이미 같으면 RowsAffected가 0이므로 존재 여부를 따로 확인
+	_, isExist, err := GetQuestionDataByQuestionID(question_id)
+	return isExist, err
+}
+
+func GetQuestionDataByQuestionID(question_id int) (QuestionData, bool, error) {
+	questions, err := selectQuestions(`WHERE q.question_id = ?`, []interface{}{question_id})
+	if err != nil || len(questions) == 0 {
+		return QuestionData{}, false, err
+	}
+	return questions[0], true, nil
+}
+
+func GetQuestions(filter QuestionFilter) ([]QuestionData, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.Category != "" {
+		conditions = append(conditions, `q.category = ?`)
+		args = append(args, filter.Category)
+	}
+	if filter.Language != "" {
+		conditions = append(conditions, `q.language = ?`)
+		args = append(args, filter.Language)
+	}
+	if filter.Is_active != -1 {
+		conditions = append(conditions, `q.is_active = `+strconv.Itoa(filter.Is_active))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = `WHERE ` + strings.Join(conditions, ` and `)
+	}
+	return selectQuestions(where, args)
+}
+
+// 질문과 트리거 단어를 같이 불러옴, 트리거 단어는 질문마다 하나의 slice로 묶음
+func selectQuestions(where string, args []interface{}) ([]QuestionData, error) {
+	r, err := db.Query(`SELECT q.question_id, q.question_contents, q.category, q.language, q.is_active, q.priority, q.answer_options, IFNULL(t.trigger_word, "") FROM question q LEFT JOIN question_trigger t ON q.question_id = t.question_id `+where+` ORDER BY q.question_id ASC, t.trigger_word ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	questions := []QuestionData{}
+	var questionData QuestionData
+	var answerOptions sql.NullString
+	var triggerWord string
+	for r.Next() {
+		err = r.Scan(&questionData.Question_id, &questionData.Question_contents, &questionData.Category, &questionData.Language, &questionData.Is_active, &questionData.Priority, &answerOptions, &triggerWord)
+		if err != nil {
+			return nil, err
+		}
+		if len(questions) == 0 || questions[len(questions)-1].Question_id != questionData.Question_id {
+			questionData.Trigger_words = []string{}
+			questionData.Answer_options = parseAnswerOptions(answerOptions)
+			questions = append(questions, questionData)
+		}
+		if triggerWord != "" {
+			last := &questions[len(questions)-1]
+			last.Trigger_words = append(last.Trigger_words, triggerWord)
+		}
+	}
+	return questions, nil
+}
+
+// 커플에게 트리거 단어로 마지막 질문이 나간 시간과 since 이후에 나간 질문 개수, 질문 쿨다운과 하루 제한에 사용
+// 오늘의 질문은 제한에 포함하지 않음
+func GetQuestionCountSince(connection_id int, since string) (int, string, error) {
+	r, err := db.Query(`SELECT IFNULL(SUM(answer_date >= ?), 0), IFNULL(MAX(answer_date), "") FROM answer WHERE connection_id = ? and is_daily = 0`, since, connection_id)
+	if err != nil {
+		return 0, "", err
+	}
+	defer r.Close()
+
+	var count int
+	var lastDate string
+	if r.Next() {
+		err = r.Scan(&count, &lastDate)
+	}
+	return count, lastDate, err
+}
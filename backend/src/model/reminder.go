@@ -0,0 +1,126 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 일정 알림 같은 시스템 메시지의 작성자, 커플마다 달라서 커플 채팅 기록과 같이 불러올 수 있음
+const systemWriterPrefix = "system-"
+
+func SystemWriterID(connection_id int) string {
+	return systemWriterPrefix + strconv.Itoa(connection_id)
+}
+
+func IsSystemWriterID(writer_id string) bool {
+	return strings.HasPrefix(writer_id, systemWriterPrefix)
+}
+
+// 일정 알림 하나와 알림을 보낼 일정
+type ReminderData struct {
+	Reminder_id int
+	Minutes_before int
+	// 마지막으로 알림을 보낸 일정 시작 시간, 반복 일정은 이후 날짜에 다시 알림을 보냄
+	Last_fired string
+	Anniversary AnniversaryData
+}
+
+// 커플 일정별 알림 시간(분), 큰 것부터
+func GetRemindersByConnID(connection_id int) (map[int][]int, error) {
+	r, err := db.Query(`SELECT anniversary_id, minutes_before FROM anniversary_reminder WHERE connection_id = ? ORDER BY minutes_before DESC`, connection_id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	reminders := make(map[int][]int)
+	for r.Next() {
+		var anniversary_id, minutes_before int
+		err = r.Scan(&anniversary_id, &minutes_before)
+		if err != nil {
+			return nil, err
+		}
+		reminders[anniversary_id] = append(reminders[anniversary_id], minutes_before)
+	}
+	return reminders, nil
+}
+
+// 일정의 알림을 minutes로 바꿈, 그대로 남는 알림은 마지막으로 보낸 기록을 유지해서 같은 알림을 다시 보내지 않음
+func ReplaceReminders(connection_id, anniversary_id int, minutes []int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM anniversary_reminder WHERE anniversary_id = ?`
+	args := []interface{}{anniversary_id}
+	if len(minutes) > 0 {
+		query += ` and minutes_before NOT IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(minutes)), ", ") + `)`
+		for _, minute := range minutes {
+			args = append(args, minute)
+		}
+	}
+	_, err = tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	for _, minute := range minutes {
+		_, err = tx.Exec(`INSERT IGNORE INTO anniversary_reminder (anniversary_id, connection_id, minutes_before) VALUES (?, ?, ?)`, anniversary_id, connection_id, minute)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// 알림을 보낼 수도 있는 일정 알림들, 반복 일정과 [from, until + 알림 시간] 사이에 시작하는 일정
+func GetDueReminders(from, until string) ([]ReminderData, error) {
+	r, err := db.Query(`SELECT r.reminder_id, r.minutes_before, r.last_fired, `+anniversaryColumns+` FROM anniversary_reminder r JOIN anniversary a ON a.anniversary_id = r.anniversary_id WHERE a.recurrence != "" or (a.start_time > ? and a.start_time <= DATE_ADD(?, INTERVAL r.minutes_before MINUTE))`, from, until)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	reminderDatas := []ReminderData{}
+	var reminderData ReminderData
+	for r.Next() {
+		err = r.Scan(append([]interface{}{&reminderData.Reminder_id, &reminderData.Minutes_before, &reminderData.Last_fired}, anniversaryScanArgs(&reminderData.Anniversary)...)...)
+		if err != nil {
+			return nil, err
+		}
+		reminderDatas = append(reminderDatas, reminderData)
+	}
+	return reminderDatas, nil
+}
+
+// 알림을 보낸 일정 시작 시간 기록, 그 사이 다른 곳에서 먼저 기록했으면 false라서 같은 알림을 두 번 보내지 않음
+func ClaimReminder(reminder_id int, last_fired, occurrence string) (bool, error) {
+	result, err := db.Exec(`UPDATE anniversary_reminder SET last_fired = ? WHERE reminder_id = ? and last_fired = ?`, occurrence, reminder_id, last_fired)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// 커플 채팅 기록에 시스템 메시지 저장
+func InsertSystemChat(connection_id int, text_body, write_time string) (int, error) {
+	result, err := db.Exec(`INSERT INTO chat (text_body, writer_id, write_time) VALUES (?, ?, ?)`, text_body, SystemWriterID(connection_id), write_time)
+	if err != nil {
+		return 0, err
+	}
+	chat_id, err := result.LastInsertId()
+	return int(chat_id), err
+}
+
+// 커플의 일정인지 확인
+func CheckAnniversaryByConnID(connection_id, anniversary_id int) (bool, error) {
+	r, err := db.Query(`SELECT anniversary_id FROM anniversary WHERE connection_id = ? and anniversary_id = ?`, connection_id, anniversary_id)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	return r.Next(), nil
+}
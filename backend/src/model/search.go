@@ -0,0 +1,131 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 채팅 검색 조건
+type SearchFilter struct {
+	Writer_ids []string
+	// MATCH AGAINST에 넣을 boolean mode 검색식, ngram 최소 길이(2자)보다 짧은 단어는 Like_terms로 검색
+	Fulltext_query string
+	Like_terms []string
+	// YYYY-MM-DD, To는 해당 날짜까지 포함
+	From string
+	To string
+	// text, file, image, voice 중 하나, 빈 값이면 전체
+	Type string
+	// true면 관련도 대신 오래된 순으로 정렬
+	Chronological bool
+	// 0이면 전체
+	Limit int
+	Offset int
+}
+
+type SearchData struct {
+	ChatData
+	Score float64 `json:"score,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+	Highlights [][2]int `json:"highlights,omitempty"`
+	// 검색된 채팅 앞뒤의 대화, 검색된 채팅 자신도 포함
+	Context []ChatData `json:"context,omitempty"`
+}
+
+// LIKE 검색어의 와일드카드 문자 이스케이프
+func escapeLike(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(term)
+}
+
+func searchCondition(filter SearchFilter) (string, []interface{}) {
+	condition, args := writerCondition(filter.Writer_ids)
+	where := ` WHERE ` + condition
+
+	if filter.Fulltext_query != "" {
+		where += ` and MATCH(c.text_body) AGAINST(? IN BOOLEAN MODE)`
+		args = append(args, filter.Fulltext_query)
+	}
+	for _, term := range filter.Like_terms {
+		where += ` and c.text_body LIKE ?`
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	if filter.From != "" {
+		where += ` and c.write_time >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where += ` and c.write_time < DATE_ADD(?, INTERVAL 1 DAY)`
+		args = append(args, filter.To)
+	}
+
+	switch filter.Type {
+	case "text":
+		where += ` and c.is_file = 0`
+	case "file":
+		where += ` and c.is_file = 1 and c.is_image = 0 and c.is_voice = 0`
+	case "image":
+		where += ` and c.is_image = 1`
+	case "voice":
+		where += ` and c.is_voice = 1`
+	}
+	return where, args
+}
+
+// 검색 조건에 맞는 채팅을 관련도 순(같으면 최신순)으로 불러오고, 전체 결과 개수도 같이 리턴
+func SearchChats(filter SearchFilter) ([]SearchData, int, error) {
+	if len(filter.Writer_ids) == 0 {
+		return nil, 0, nil
+	}
+	where, args := searchCondition(filter)
+
+	r1, err := db.Query(`SELECT COUNT(*) FROM chat c`+where, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := 0
+	if r1.Next() {
+		err = r1.Scan(&total)
+	}
+	r1.Close()
+	if err != nil || total == 0 {
+		return nil, total, err
+	}
+
+	score := `0`
+	scoreArgs := []interface{}{}
+	if filter.Fulltext_query != "" {
+		score = `MATCH(c.text_body) AGAINST(? IN BOOLEAN MODE)`
+		scoreArgs = append(scoreArgs, filter.Fulltext_query)
+	}
+
+	order := ` ORDER BY score DESC, c.chat_id DESC`
+	if filter.Chronological {
+		order = ` ORDER BY c.chat_id ASC`
+	}
+	limit := ``
+	if filter.Limit > 0 {
+		limit = ` LIMIT ` + strconv.Itoa(filter.Limit) + ` OFFSET ` + strconv.Itoa(filter.Offset)
+	}
+
+	r2, err := db.Query(`SELECT c.chat_id, c.writer_id, c.write_time, c.text_body, c.is_file, c.is_image, c.is_voice, COALESCE(a.duration_ms, 0), COALESCE(a.waveform, ''), `+score+` AS score
+		FROM chat c LEFT JOIN attachment a ON a.chat_id = c.chat_id`+where+`
+		`+order+limit, append(scoreArgs, args...)...)
+	if err != nil {
+		return nil, total, err
+	}
+	defer r2.Close()
+
+	var searchData SearchData
+	var searchDatas []SearchData
+	var waveform string
+	for r2.Next() {
+		err = r2.Scan(&searchData.Chat_id, &searchData.Writer_id, &searchData.Write_time, &searchData.Text_body, &searchData.Is_file, &searchData.Is_image, &searchData.Is_voice, &searchData.Duration_ms, &waveform, &searchData.Score)
+		if err != nil {
+			return nil, total, err
+		}
+		searchData.Waveform = parseWaveform(waveform)
+		searchDatas = append(searchDatas, searchData)
+	}
+	return searchDatas, total, nil
+}
@@ -0,0 +1,68 @@
+package model
+
+// 날짜(시간 단위)별로 묶은 한 사람의 감정 점수
+type HourlySentimentData struct {
+	Writer_id string
+	Hour string
+	Score_sum float64
+	Messages int
+	Positive int
+	Negative int
+}
+
+// 커플이 감정 분석을 끄지 않았는지 확인, 설정한 적 없으면 켜짐
+func IsSentimentEnabled(connection_id int) (bool, error) {
+	r, err := db.Query(`SELECT is_enabled FROM mood_setting WHERE connection_id = ?`, connection_id)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	if !r.Next() {
+		return true, nil
+	}
+	var is_enabled int
+	err = r.Scan(&is_enabled)
+	return is_enabled == 1, err
+}
+
+// 감정 분석 켜기/끄기, 끄면 저장된 점수도 모두 삭제
+func UpdateSentimentEnabled(connection_id, is_enabled int) error {
+	_, err := db.Exec(`INSERT INTO mood_setting (connection_id, is_enabled) VALUES (?, ?) ON DUPLICATE KEY UPDATE is_enabled = VALUES(is_enabled)`, connection_id, is_enabled)
+	if err != nil || is_enabled == 1 {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM chat_sentiment WHERE connection_id = ?`, connection_id)
+	return err
+}
+
+func InsertChatSentiment(chat_id, connection_id int, writer_id, write_time string, score float64) error {
+	_, err := db.Exec(`INSERT INTO chat_sentiment (chat_id, connection_id, writer_id, write_time, score) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE score = VALUES(score)`, chat_id, connection_id, writer_id, write_time, score)
+	return err
+}
+
+func DeleteChatSentiment(chat_id, connection_id int) error {
+	_, err := db.Exec(`DELETE FROM chat_sentiment WHERE chat_id = ? and connection_id = ?`, chat_id, connection_id)
+	return err
+}
+
+// from 이상 to 미만(DB 시간 형식)의 감정 점수를 사람, 시간(YYYY-MM-DD HH:00:00)별로 합산
+// threshold보다 크면 긍정, -threshold보다 작으면 부정 채팅으로 셈
+func GetHourlySentiments(connection_id int, from, to string, threshold float64) ([]HourlySentimentData, error) {
+	r, err := db.Query(`SELECT writer_id, DATE_FORMAT(write_time, '%Y-%m-%d %H:00:00') AS hour, SUM(score), COUNT(*), SUM(score > ?), SUM(score < ?) FROM chat_sentiment WHERE connection_id = ? and write_time >= ? and write_time < ? GROUP BY writer_id, hour`, threshold, -threshold, connection_id, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	hourlySentimentDatas := []HourlySentimentData{}
+	var hourlySentimentData HourlySentimentData
+	for r.Next() {
+		err = r.Scan(&hourlySentimentData.Writer_id, &hourlySentimentData.Hour, &hourlySentimentData.Score_sum, &hourlySentimentData.Messages, &hourlySentimentData.Positive, &hourlySentimentData.Negative)
+		if err != nil {
+			return nil, err
+		}
+		hourlySentimentDatas = append(hourlySentimentDatas, hourlySentimentData)
+	}
+	return hourlySentimentDatas, nil
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"strconv"
+)
+
+// 이어받기 가능한 분할 업로드 세션
+type UploadData struct {
+	Upload_id string `json:"upload_id"`
+	UUID string `json:"-"`
+	Connection_id int `json:"-"`
+	File_name string `json:"file_name"`
+	File_size int64 `json:"file_size"`
+	Checksum string `json:"sha256"`
+}
+
+func InsertUpload(data UploadData) error {
+	_, err := db.Exec(`INSERT INTO upload (upload_id, uuid, connection_id, file_name, file_size, checksum, updated_at) VALUES (?, ?, ?, ?, ?, ?, NOW())`, data.Upload_id, data.UUID, data.Connection_id, data.File_name, data.File_size, data.Checksum)
+	return err
+}
+
+func GetUploadByUploadID(upload_id string) (UploadData, bool, error) {
+	var uploadData UploadData
+
+	r, err := db.Query(`SELECT upload_id, uuid, connection_id, file_name, file_size, checksum FROM upload WHERE upload_id = ?`, upload_id)
+	if err != nil {
+		return uploadData, false, err
+	}
+	defer r.Close()
+
+	if !r.Next() {
+		return uploadData, false, nil
+	}
+	err = r.Scan(&uploadData.Upload_id, &uploadData.UUID, &uploadData.Connection_id, &uploadData.File_name, &uploadData.File_size, &uploadData.Checksum)
+	if err != nil {
+		return uploadData, false, err
+	}
+	return uploadData, true, nil
+}
+
+// 조각을 받을 때마다 갱신해서 진행중인 업로드가 만료되지 않도록 함
+func TouchUploadByUploadID(upload_id string) error {
+	_, err := db.Exec(`UPDATE upload SET updated_at = NOW() WHERE upload_id = ?`, upload_id)
+	return err
+}
+
+func DeleteUploadByUploadID(upload_id string) error {
+	_, err := db.Exec(`DELETE FROM upload WHERE upload_id = ?`, upload_id)
+	return err
+}
+
+// hours 시간 이상 조각이 들어오지 않은 업로드 목록
+func GetExpiredUploadIDs(hours int) ([]string, error) {
+	r, err := db.Query("SELECT upload_id FROM upload WHERE updated_at < DATE_ADD(NOW(), INTERVAL -"+strconv.Itoa(hours)+" HOUR)")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var upload_id string
+	var upload_ids []string
+	for r.Next() {
+		r.Scan(&upload_id)
+		upload_ids = append(upload_ids, upload_id)
+	}
+	return upload_ids, nil
+}
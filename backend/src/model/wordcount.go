@@ -0,0 +1,203 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 단어별 사용 횟수
+type WordCountData struct {
+	Word string `json:"word"`
+	Count int `json:"count"`
+}
+
+// 커플의 한 사람이 date(YYYY-MM-DD)에 사용한 단어 횟수를 더함, 채팅 삭제 시에는 음수로 빼고 0 이하가 된 단어는 삭제
+func AddWordCounts(connection_id int, uuid, date string, counts map[string]int) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	values := []string{}
+	args := []interface{}{}
+	hasNegative := false
+	for word, count := range counts {
+		values = append(values, `(?, ?, ?, ?, ?)`)
+		args = append(args, connection_id, uuid, date, word, count)
+		if count < 0 {
+			hasNegative = true
+		}
+	}
+
+	_, err := db.Exec(`INSERT INTO word_count (connection_id, uuid, count_date, word, count) VALUES `+strings.Join(values, `, `)+` ON DUPLICATE KEY UPDATE count = count + VALUES(count)`, args...)
+	if err != nil || !hasNegative {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM word_count WHERE connection_id = ? and uuid = ? and count_date = ? and count <= 0`, connection_id, uuid, date)
+	return err
+}
+
+// 두 사람이 모두 사용한 단어와 각자 사용한 횟수
+type SharedWordData struct {
+	Word string `json:"word"`
+	My_count int `json:"my_count"`
+	Other_count int `json:"other_count"`
+}
+
+// from, to(YYYY-MM-DD, 둘 다 포함) 기간의 단어 횟수 조회 조건, 빈 값이면 기간 제한 없음
+// 커플 제외 단어와 보는 사람(viewer_uuid)의 제외 단어는 빼고 집계, 제외 단어의 *는 LIKE의 %로 바꿔 비교
+func wordCountCondition(connection_id int, viewer_uuid, from, to string) (string, []interface{}) {
+	condition := `WHERE connection_id = ? and NOT EXISTS (SELECT 1 FROM exceptionword e WHERE e.connection_id = word_count.connection_id and e.uuid IN ("", ?) and word_count.word LIKE REPLACE(e.except_word, "*", "%"))`
+	args := []interface{}{connection_id, viewer_uuid}
+	if from != "" {
+		condition += ` and count_date >= ?`
+		args = append(args, from)
+	}
+	if to != "" {
+		condition += ` and count_date <= ?`
+		args = append(args, to)
+	}
+	return condition, args
+}
+
+// 기간 안에 uuid가 가장 많이 사용한 단어를 limit개까지 리턴
+func GetTopWords(connection_id int, viewer_uuid, uuid, from, to string, limit int) ([]WordCountData, error) {
+	condition, args := wordCountCondition(connection_id, viewer_uuid, from, to)
+	args = append(args, uuid)
+
+	r, err := db.Query(`SELECT word, SUM(count) AS total FROM word_count `+condition+` and uuid = ? GROUP BY word ORDER BY total DESC, word ASC LIMIT `+strconv.Itoa(limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	wordCountDatas := []WordCountData{}
+	var wordCountData WordCountData
+	for r.Next() {
+		err = r.Scan(&wordCountData.Word, &wordCountData.Count)
+		if err != nil {
+			return nil, err
+		}
+		wordCountDatas = append(wordCountDatas, wordCountData)
+	}
+	return wordCountDatas, nil
+}
+
+// 기간 안에 두 사람이 모두 사용한 단어를 합계가 많은 순으로 limit개까지 리턴
+func GetSharedWords(connection_id int, my_uuid, from, to string, limit int) ([]SharedWordData, error) {
+	condition, args := wordCountCondition(connection_id, my_uuid, from, to)
+	args = append([]interface{}{my_uuid, my_uuid}, args...)
+
+	r, err := db.Query(`SELECT word, SUM(IF(uuid = ?, count, 0)) AS my_count, SUM(IF(uuid != ?, count, 0)) AS other_count FROM word_count `+condition+` GROUP BY word HAVING my_count > 0 and other_count > 0 ORDER BY my_count + other_count DESC, word ASC LIMIT `+strconv.Itoa(limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	sharedWordDatas := []SharedWordData{}
+	var sharedWordData SharedWordData
+	for r.Next() {
+		err = r.Scan(&sharedWordData.Word, &sharedWordData.My_count, &sharedWordData.Other_count)
+		if err != nil {
+			return nil, err
+		}
+		sharedWordDatas = append(sharedWordDatas, sharedWordData)
+	}
+	return sharedWordDatas, nil
+}
+
+// 기간 안에 uuid가 words 각각을 사용한 횟수, 사용하지 않은 단어는 map에 없음
+func GetWordCountsByWords(connection_id int, viewer_uuid, uuid, from, to string, words []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(words) == 0 {
+		return counts, nil
+	}
+
+	condition, args := wordCountCondition(connection_id, viewer_uuid, from, to)
+	args = append(args, uuid)
+	for _, word := range words {
+		args = append(args, word)
+	}
+
+	r, err := db.Query(`SELECT word, SUM(count) FROM word_count `+condition+` and uuid = ? and word IN (`+strings.TrimSuffix(strings.Repeat("?, ", len(words)), ", ")+`) GROUP BY word`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var word string
+	var count int
+	for r.Next() {
+		err = r.Scan(&word, &count)
+		if err != nil {
+			return nil, err
+		}
+		counts[word] = count
+	}
+	return counts, nil
+}
+
+// 단어 횟수 집계에 필요한 채팅 정보, 채팅이 없으면 false
+func GetChatForWordCount(chat_id int) (ChatData, bool, error) {
+	var chatData ChatData
+
+	r, err := db.Query(`SELECT writer_id, text_body, write_time, is_file FROM chat WHERE chat_id = ?`, chat_id)
+	if err != nil {
+		return chatData, false, err
+	}
+	defer r.Close()
+
+	if !r.Next() {
+		return chatData, false, nil
+	}
+	err = r.Scan(&chatData.Writer_id, &chatData.Text_body, &chatData.Write_time, &chatData.Is_file)
+	return chatData, err == nil, err
+}
+
+// 모든 커플의 connection_id와 두 사람의 uuid
+func GetAllConnections() ([]int, [][2]string, error) {
+	r, err := db.Query(`SELECT connection_id, first_usr, second_usr FROM connection`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	connIDs := []int{}
+	usrs := [][2]string{}
+	var connID int
+	var first_usr, second_usr string
+	for r.Next() {
+		err = r.Scan(&connID, &first_usr, &second_usr)
+		if err != nil {
+			return nil, nil, err
+		}
+		connIDs = append(connIDs, connID)
+		usrs = append(usrs, [2]string{first_usr, second_usr})
+	}
+	return connIDs, usrs, nil
+}
+
+// 커플의 텍스트 채팅 전체, 단어 횟수를 다시 계산할 때 사용
+func GetTextChatsByWriterIDs(writer_ids []string) ([]ChatData, error) {
+	condition, args := writerCondition(writer_ids)
+	r, err := db.Query(`SELECT writer_id, text_body, write_time FROM chat c WHERE `+condition+` and c.is_file = 0`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	chatDatas := []ChatData{}
+	var chatData ChatData
+	for r.Next() {
+		err = r.Scan(&chatData.Writer_id, &chatData.Text_body, &chatData.Write_time)
+		if err != nil {
+			return nil, err
+		}
+		chatDatas = append(chatDatas, chatData)
+	}
+	return chatDatas, nil
+}
+
+func DeleteWordCountsByConnID(connection_id int) error {
+	_, err := db.Exec(`DELETE FROM word_count WHERE connection_id = ?`, connection_id)
+	return err
+}
@@ -20,7 +20,10 @@ CREATE TABLE `chat` (
         `text_body` TEXT NOT NULL,
         `is_answer` TINYINT(1) DEFAULT 0,
         `is_file` TINYINT(1) DEFAULT 0,
-        `is_image` TINYINT(1) DEFAULT 0);
+        `is_image` TINYINT(1) DEFAULT 0,
+        `is_voice` TINYINT(1) DEFAULT 0,
+        INDEX (`writer_id`),
+        FULLTEXT INDEX `ft_text_body` (`text_body`) WITH PARSER ngram);
 
 CREATE TABLE `request` (
         `request_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
@@ -43,8 +46,36 @@ CREATE TABLE `beabouttodelete` (
 
 CREATE TABLE `question` (
         `question_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
-        `target_word` VARCHAR(255) NOT NULL,
-        `question_contents` VARCHAR(255) NOT NULL);
+        `question_contents` VARCHAR(255) NOT NULL,
+        `category` VARCHAR(50) NOT NULL DEFAULT 'general',
+        `language` VARCHAR(35) NOT NULL DEFAULT 'ko',
+        `is_active` TINYINT NOT NULL DEFAULT 1,
+        `priority` INT NOT NULL DEFAULT 0,
+        `answer_options` TEXT,
+        INDEX (`category`),
+        INDEX (`language`));
+
+CREATE TABLE `question_trigger` (
+        `question_id` INT NOT NULL,
+        `trigger_word` VARCHAR(255) NOT NULL,
+        PRIMARY KEY (`question_id`, `trigger_word`),
+        FOREIGN KEY (`question_id`) REFERENCES `question`(`question_id`) ON UPDATE CASCADE ON DELETE CASCADE);
+
+-- 이 파일은 chatdb를 지우고 새로 만들기 때문에, 기존 데이터를 유지하려면 이 파일 대신 아래 쿼리를 직접 실행
+-- question.target_word를 question_trigger로 옮긴 뒤에 컬럼을 지워야 기존 질문의 트리거 단어가 사라지지 않음
+-- ALTER TABLE `question`
+--         ADD COLUMN `category` VARCHAR(50) NOT NULL DEFAULT 'general',
+--         ADD COLUMN `language` VARCHAR(35) NOT NULL DEFAULT 'ko',
+--         ADD COLUMN `is_active` TINYINT NOT NULL DEFAULT 1,
+--         ADD INDEX (`category`),
+--         ADD INDEX (`language`);
+-- CREATE TABLE `question_trigger` (
+--         `question_id` INT NOT NULL,
+--         `trigger_word` VARCHAR(255) NOT NULL,
+--         PRIMARY KEY (`question_id`, `trigger_word`),
+--         FOREIGN KEY (`question_id`) REFERENCES `question`(`question_id`) ON UPDATE CASCADE ON DELETE CASCADE);
+-- INSERT IGNORE INTO `question_trigger` (`question_id`, `trigger_word`) SELECT `question_id`, `target_word` FROM `question` WHERE `target_word` <> '';
+-- ALTER TABLE `question` DROP COLUMN `target_word`;
 
 CREATE TABLE `answer` (
         `answer_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
@@ -53,12 +84,53 @@ CREATE TABLE `answer` (
         `second_answer` VARCHAR(255) DEFAULT 'not-written',
         `answer_date` VARCHAR(255) NOT NULL,
         `question_id` INT,
+        `is_daily` TINYINT NOT NULL DEFAULT 0,
+        UNIQUE KEY `connection_question` (`connection_id`, `question_id`),
         FOREIGN KEY (`question_id`) REFERENCES `question`(`question_id`) ON UPDATE CASCADE ON DELETE CASCADE);
 
+CREATE TABLE `pending_question` (
+        `connection_id` INT NOT NULL,
+        `question_id` INT NOT NULL,
+        `uuid` VARCHAR(255) NOT NULL,
+        `status` VARCHAR(10) NOT NULL,
+        `defer_until` VARCHAR(19) DEFAULT NULL,
+        PRIMARY KEY (`connection_id`, `question_id`, `uuid`),
+        FOREIGN KEY (`question_id`) REFERENCES `question`(`question_id`) ON UPDATE CASCADE ON DELETE CASCADE);
+
+CREATE TABLE `daily_question` (
+        `connection_id` INT NOT NULL PRIMARY KEY,
+        `is_enabled` TINYINT NOT NULL DEFAULT 0,
+        `send_time` VARCHAR(5) NOT NULL DEFAULT '21:00',
+        `timezone` VARCHAR(64) NOT NULL DEFAULT 'Asia/Seoul',
+        `category` VARCHAR(50) NOT NULL DEFAULT '',
+        `last_sent_date` VARCHAR(10) NOT NULL DEFAULT '');
+
+CREATE TABLE `word_count` (
+        `connection_id` INT NOT NULL,
+        `uuid` VARCHAR(255) NOT NULL,
+        `count_date` DATE NOT NULL,
+        `word` VARCHAR(50) NOT NULL,
+        `count` INT NOT NULL DEFAULT 0,
+        PRIMARY KEY (`connection_id`, `uuid`, `count_date`, `word`));
+
+CREATE TABLE `chat_sentiment` (
+        `chat_id` INT NOT NULL PRIMARY KEY,
+        `connection_id` INT NOT NULL,
+        `writer_id` VARCHAR(255) NOT NULL,
+        `write_time` DATETIME NOT NULL,
+        `score` FLOAT NOT NULL DEFAULT 0,
+        INDEX (`connection_id`, `write_time`));
+
+CREATE TABLE `mood_setting` (
+        `connection_id` INT NOT NULL PRIMARY KEY,
+        `is_enabled` TINYINT NOT NULL DEFAULT 1);
+
 CREATE TABLE `exceptionword` (
         `exception_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
         `connection_id` INT NOT NULL,
-        `except_word` TEXT NOT NULL);
+        `uuid` VARCHAR(255) NOT NULL DEFAULT "",
+        `except_word` VARCHAR(50) NOT NULL,
+        UNIQUE KEY `connection_uuid_word` (`connection_id`, `uuid`, `except_word`));
 
 CREATE TABLE `anniversary` (
         `anniversary_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
@@ -67,7 +139,69 @@ CREATE TABLE `anniversary` (
         `month` INT NOT NULL,
         `date` INT NOT NULL,
         `contents` VARCHAR(255) NOT NULL,
-        `d_day` TINYINT(1) NOT NULL);
+        `d_day` TINYINT(1) NOT NULL,
+        `recurrence` VARCHAR(10) NOT NULL DEFAULT '',
+        `interval_days` INT NOT NULL DEFAULT 0,
+        `is_lunar` TINYINT(1) NOT NULL DEFAULT 0,
+        `is_leap_month` TINYINT(1) NOT NULL DEFAULT 0,
+        `start_time` DATETIME NOT NULL,
+        `end_time` DATETIME NOT NULL,
+        `is_all_day` TINYINT(1) NOT NULL DEFAULT 1,
+        `timezone` VARCHAR(64) NOT NULL DEFAULT 'Asia/Seoul',
+        `location` VARCHAR(255) NOT NULL DEFAULT '',
+        `notes` TEXT NOT NULL,
+        INDEX (`connection_id`, `start_time`));
+
+CREATE TABLE `anniversary_reminder` (
+        `reminder_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
+        `anniversary_id` INT NOT NULL,
+        `connection_id` INT NOT NULL,
+        `minutes_before` INT NOT NULL,
+        `last_fired` DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00',
+        UNIQUE KEY `anniversary_minutes` (`anniversary_id`, `minutes_before`),
+        INDEX (`connection_id`));
+
+CREATE TABLE `calendar_feed` (
+        `connection_id` INT NOT NULL PRIMARY KEY,
+        `token` VARCHAR(64) NOT NULL,
+        UNIQUE KEY `token` (`token`));
+
+CREATE TABLE `attachment` (
+        `attachment_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
+        `chat_id` INT NOT NULL,
+        `connection_id` INT NOT NULL,
+        `file_name` VARCHAR(255) NOT NULL,
+        `mime_type` VARCHAR(100) NOT NULL,
+        `file_size` BIGINT NOT NULL,
+        `duration_ms` INT DEFAULT 0,
+        `waveform` TEXT,
+        INDEX (`chat_id`),
+        INDEX (`connection_id`));
+
+CREATE TABLE `upload` (
+        `upload_id` VARCHAR(36) NOT NULL PRIMARY KEY,
+        `uuid` VARCHAR(255) NOT NULL,
+        `connection_id` INT NOT NULL,
+        `file_name` VARCHAR(255) NOT NULL,
+        `file_size` BIGINT NOT NULL,
+        `checksum` VARCHAR(64) NOT NULL,
+        `updated_at` DATETIME NOT NULL);
+
+CREATE TABLE `photo_favorite` (
+        `connection_id` INT NOT NULL,
+        `chat_id` INT NOT NULL,
+        PRIMARY KEY (`connection_id`, `chat_id`));
+
+CREATE TABLE `album` (
+        `album_id` INT AUTO_INCREMENT NOT NULL PRIMARY KEY,
+        `connection_id` INT NOT NULL,
+        `album_name` VARCHAR(100) NOT NULL);
+
+CREATE TABLE `album_photo` (
+        `album_id` INT NOT NULL,
+        `chat_id` INT NOT NULL,
+        PRIMARY KEY (`album_id`, `chat_id`),
+        FOREIGN KEY (`album_id`) REFERENCES `album`(`album_id`) ON DELETE CASCADE);
 
 -- 이거 작동 안함 왜 그런겨? chat DB create까지만 작동함
 